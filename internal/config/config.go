@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config holds all configuration for the application
 type Config struct {
@@ -10,6 +15,42 @@ type Config struct {
 	RAWGAPIKey   string
 	UploadDir    string
 	ModeratorKey string
+
+	// RAWGBaseURL is the RAWG API root; tests point it at a fake server
+	RAWGBaseURL string
+	// RAWGTimeout bounds each request to RAWG
+	RAWGTimeout time.Duration
+
+	// RAWG responses are cached for a TTL, then served stale for RAWGCacheStaleFor while
+	// they are refreshed in the background
+	RAWGSearchCacheTTL time.Duration
+	RAWGGameCacheTTL   time.Duration
+	RAWGCacheStaleFor  time.Duration
+
+	// RAWGSyncInterval is how often up to RAWGSyncBatchSize imported games not synced within
+	// RAWGSyncMaxAge are refreshed from RAWG; the sync job does not run when it is zero
+	RAWGSyncInterval  time.Duration
+	RAWGSyncBatchSize int
+	RAWGSyncMaxAge    time.Duration
+
+	// DuplicatePostMode is 'warn' (default), 'block' to reject exact duplicates, or 'off'
+	DuplicatePostMode string
+
+	// Reactions is the standard reaction set available on every post and comment
+	Reactions []string
+
+	// CommentRateLimit is how many comments a user may post per CommentRateWindow; accounts
+	// younger than NewAccountAge get NewAccountCommentRateLimit instead. Zero disables a limit.
+	CommentRateLimit           int
+	NewAccountCommentRateLimit int
+	CommentRateWindow          time.Duration
+	NewAccountAge              time.Duration
+
+	// ViewFlushInterval is how often buffered post views are written
+	ViewFlushInterval time.Duration
+
+	// LinkPreviewAllowPrivate lets link previews reach private addresses (local testing only)
+	LinkPreviewAllowPrivate bool
 }
 
 // Load returns the application configuration
@@ -21,6 +62,27 @@ func Load() *Config {
 		RAWGAPIKey:   getEnv("RAWG_API_KEY", "5e3f8883fe504827bf672e7bc73cbdee"),
 		UploadDir:    getEnv("UPLOAD_DIR", "./uploads"),
 		ModeratorKey: getEnv("MODERATOR_KEY", "moderator123"),
+
+		RAWGBaseURL: getEnv("RAWG_BASE_URL", "https://api.rawg.io/api"),
+		RAWGTimeout: getEnvDuration("RAWG_TIMEOUT", 10*time.Second),
+
+		RAWGSearchCacheTTL: getEnvDuration("RAWG_SEARCH_CACHE_TTL", time.Hour),
+		RAWGGameCacheTTL:   getEnvDuration("RAWG_GAME_CACHE_TTL", 24*time.Hour),
+		RAWGCacheStaleFor:  getEnvDuration("RAWG_CACHE_STALE_FOR", 24*time.Hour),
+
+		RAWGSyncInterval:  getEnvOptionalDuration("RAWG_SYNC_INTERVAL", time.Hour),
+		RAWGSyncBatchSize: getEnvInt("RAWG_SYNC_BATCH_SIZE", 20),
+		RAWGSyncMaxAge:    getEnvDuration("RAWG_SYNC_MAX_AGE", 24*time.Hour),
+
+		DuplicatePostMode:       getEnv("DUPLICATE_POST_MODE", "warn"),
+		Reactions:               getEnvList("REACTIONS", []string{"👍", "❤️", "😂", "😮", "😢", "🔥"}),
+		ViewFlushInterval:       getEnvDuration("VIEW_FLUSH_INTERVAL", 10*time.Second),
+		LinkPreviewAllowPrivate: getEnv("LINK_PREVIEW_ALLOW_PRIVATE", "false") == "true",
+
+		CommentRateLimit:           getEnvInt("COMMENT_RATE_LIMIT", 10),
+		NewAccountCommentRateLimit: getEnvInt("NEW_ACCOUNT_COMMENT_RATE_LIMIT", 3),
+		CommentRateWindow:          getEnvDuration("COMMENT_RATE_WINDOW", 10*time.Minute),
+		NewAccountAge:              getEnvDuration("NEW_ACCOUNT_AGE", 24*time.Hour),
 	}
 }
 
@@ -31,3 +93,41 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt returns a non-negative integer environment variable or a default value
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration returns a duration environment variable or a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvOptionalDuration is getEnvDuration for settings where zero turns a feature off
+func getEnvOptionalDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList returns a comma-separated environment variable or a default value
+func getEnvList(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"forumapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CounterReport summarizes how many rows had drifted denormalized counters
+type CounterReport struct {
+	PostCommentCounts  int64
+	CommentReplyCounts int64
+	CommentVoteCounts  int64
+}
+
+// ReconcileCounters recomputes the denormalized counters from the source tables
+// and fixes any rows that have drifted
+func ReconcileCounters(db *gorm.DB) (CounterReport, error) {
+	var report CounterReport
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		const commentCount = "(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id)"
+		result := tx.Exec("UPDATE posts SET comment_count = " + commentCount + " WHERE comment_count <> " + commentCount)
+		if result.Error != nil {
+			return result.Error
+		}
+		report.PostCommentCounts = result.RowsAffected
+
+		const replyCount = "(SELECT COUNT(*) FROM comments AS replies WHERE replies.parent_id = comments.id)"
+		result = tx.Exec("UPDATE comments SET reply_count = " + replyCount + " WHERE reply_count <> " + replyCount)
+		if result.Error != nil {
+			return result.Error
+		}
+		report.CommentReplyCounts = result.RowsAffected
+
+		const upvotes = "(SELECT COUNT(*) FROM comment_votes WHERE comment_votes.comment_id = comments.id AND value = 1)"
+		const downvotes = "(SELECT COUNT(*) FROM comment_votes WHERE comment_votes.comment_id = comments.id AND value = -1)"
+		var drifted []struct {
+			ID        uint
+			Upvotes   int
+			Downvotes int
+		}
+		if err := tx.Raw("SELECT id, " + upvotes + " AS upvotes, " + downvotes + " AS downvotes FROM comments " +
+			"WHERE upvotes <> " + upvotes + " OR downvotes <> " + downvotes + " OR score <> upvotes - downvotes").
+			Scan(&drifted).Error; err != nil {
+			return err
+		}
+		for _, c := range drifted {
+			if err := tx.Model(&models.Comment{}).Where("id = ?", c.ID).UpdateColumns(map[string]interface{}{
+				"upvotes":    c.Upvotes,
+				"downvotes":  c.Downvotes,
+				"score":      c.Upvotes - c.Downvotes,
+				"best_score": models.WilsonScore(c.Upvotes, c.Downvotes),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		report.CommentVoteCounts = int64(len(drifted))
+
+		return nil
+	})
+
+	return report, err
+}
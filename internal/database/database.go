@@ -2,6 +2,8 @@ package database
 
 import (
 	"log"
+	"regexp"
+	"strings"
 
 	"forumapp/internal/config"
 	"forumapp/internal/models"
@@ -15,26 +17,119 @@ var DB *gorm.DB
 // Initialize sets up the database connection and runs migrations
 func Initialize(cfg *config.Config) *gorm.DB {
 	var err error
-	DB, err = gorm.Open(sqlite.Open(cfg.DatabasePath), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(withForeignKeys(cfg.DatabasePath)), &gorm.Config{})
 	if err != nil {
 		log.Fatal("failed to connect database: ", err)
 	}
 
-	// AutoMigrate the schema
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.Game{},
-		&models.Tag{},
-		&models.Post{},
-		&models.Comment{},
-	)
+	// Older databases need data backfilled into newly added columns and tables
+	needsCounters := DB.Migrator().HasTable("posts") && !DB.Migrator().HasColumn("posts", "comment_count")
+	needsGameLinks := DB.Migrator().HasTable("posts") && !DB.Migrator().HasTable("post_games")
+
+	// Migrate on one connection with foreign keys off: rebuilding a table drops
+	// the old one, which would otherwise cascade deletes into its children
+	err = DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		if err := conn.AutoMigrate(
+			&models.User{},
+			&models.Game{},
+			&models.Tag{},
+			&models.Platform{},
+			&models.Post{},
+			&models.Comment{},
+			&models.LinkPreview{},
+			&models.PostView{},
+			&models.ModerationAction{},
+			&models.SavedItem{},
+			&models.Report{},
+			&models.Reaction{},
+			&models.CustomReaction{},
+			&models.Mention{},
+			&models.ThreadSubscription{},
+			&models.CommentVote{},
+			&models.RAWGCacheEntry{},
+		); err != nil {
+			return err
+		}
+		return upgradeForeignKeys(conn)
+	})
 	if err != nil {
 		log.Fatal("failed to migrate database: ", err)
 	}
 
+	// Link existing posts to their primary game
+	if needsGameLinks {
+		if err := DB.Exec("INSERT OR IGNORE INTO post_games (post_id, game_id) SELECT id, game_id FROM posts WHERE game_id IS NOT NULL").Error; err != nil {
+			log.Fatal("failed to backfill post games: ", err)
+		}
+	}
+
+	// Compute counters for posts created before they were stored
+	if needsCounters {
+		if _, err := ReconcileCounters(DB); err != nil {
+			log.Fatal("failed to backfill counters: ", err)
+		}
+	}
+
 	return DB
 }
 
+// withForeignKeys turns on SQLite foreign key enforcement for every connection
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=1"
+	}
+	return dsn + "?_foreign_keys=1"
+}
+
+// cascadingRelations are the relations whose foreign keys carry ON DELETE actions
+var cascadingRelations = []struct {
+	model    interface{}
+	relation string
+}{
+	{&models.Post{}, "Comments"},
+	{&models.Post{}, "LinkPreview"},
+	{&models.Post{}, "Views"},
+	{&models.Post{}, "Subscriptions"},
+	{&models.Comment{}, "Replies"},
+	{&models.Comment{}, "Votes"},
+	{&models.Game{}, "Posts"},
+}
+
+// upgradeForeignKeys rebuilds foreign keys created before they had ON DELETE actions.
+// AutoMigrate only adds missing constraints and leaves existing ones untouched.
+func upgradeForeignKeys(conn *gorm.DB) error {
+	for _, r := range cascadingRelations {
+		stmt := &gorm.Statement{DB: conn}
+		if err := stmt.Parse(r.model); err != nil {
+			return err
+		}
+		constraint := stmt.Schema.Relationships.Relations[r.relation].ParseConstraint()
+
+		var ddl string
+		if err := conn.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", constraint.Schema.Table).
+			Scan(&ddl).Error; err != nil {
+			return err
+		}
+		current := regexp.MustCompile("CONSTRAINT `" + regexp.QuoteMeta(constraint.Name) + "` FOREIGN KEY [^,]*").FindString(ddl)
+		if current == "" || strings.Contains(current, "ON DELETE") {
+			continue
+		}
+
+		if err := conn.Migrator().DropConstraint(r.model, constraint.Name); err != nil {
+			return err
+		}
+		if err := conn.Migrator().CreateConstraint(r.model, constraint.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event is a domain event published after a change has been committed
+type Event interface {
+	// Name identifies the kind of event subscribers register for
+	Name() string
+}
+
+// UserMentionedEvent is the name of UserMentioned events
+const UserMentionedEvent = "user.mentioned"
+
+// UserMentioned is published when a post or comment newly mentions a user
+type UserMentioned struct {
+	MentionID       uint
+	MentionedUserID uint
+	AuthorID        uint
+	TargetType      string // 'post' or 'comment'
+	TargetID        uint
+	PostID          uint
+	CreatedAt       time.Time
+}
+
+// Name implements Event
+func (UserMentioned) Name() string { return UserMentionedEvent }
+
+// Handler consumes a published event
+type Handler func(Event)
+
+// Bus is an in-process publish/subscribe dispatcher for domain events.
+// Handlers run synchronously in the publishing goroutine and should hand
+// slow work off to their own goroutines.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers a handler for events with the given name
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish delivers an event to its subscribers; a panicking handler does not affect the others
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[event.Name()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("event handler for %s panicked: %v", event.Name(), r)
+				}
+			}()
+			handler(event)
+		}()
+	}
+}
@@ -0,0 +1,237 @@
+// Package gamesync keeps games imported from RAWG up to date with their RAWG metadata
+package gamesync
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"forumapp/internal/models"
+	"forumapp/internal/rawg"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLocalGame is returned when syncing a game that was not imported from RAWG
+var ErrLocalGame = errors.New("game was not imported from RAWG")
+
+// Options configures a Syncer
+type Options struct {
+	Interval  time.Duration // How often a batch is synced
+	BatchSize int           // Most games refreshed per batch, i.e. the RAWG request budget per Interval
+	MaxAge    time.Duration // Games synced more recently than this are skipped
+	Pace      time.Duration // Delay between RAWG requests within a batch
+}
+
+// Syncer periodically refreshes the metadata of games imported from RAWG, least recently
+// synced first
+type Syncer struct {
+	db     *gorm.DB
+	client rawg.Client
+	opts   Options
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a Syncer; call Start to begin syncing in the background
+func New(db *gorm.DB, client rawg.Client, opts Options) *Syncer {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 24 * time.Hour
+	}
+	if opts.Pace <= 0 {
+		opts.Pace = time.Second
+	}
+
+	return &Syncer{
+		db:     db,
+		client: client,
+		opts:   opts,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs a batch every Interval in the background
+func (s *Syncer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+			synced, err := s.SyncBatch(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("RAWG game sync stopped after %d games: %v", synced, err)
+			}
+		}
+	}()
+}
+
+// Stop cancels any batch in progress and stops the background loop
+func (s *Syncer) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// SyncBatch refreshes up to BatchSize games that are due, returning how many were updated.
+// Games missing from RAWG or failing to sync keep their metadata and go to the back of the
+// queue, so they cannot crowd out the others; the batch ends early when RAWG rate limits us.
+func (s *Syncer) SyncBatch(ctx context.Context) (int, error) {
+	var games []models.Game
+	if err := s.db.Where("is_local = ? AND rawg_id > 0", false).
+		Where("last_synced_at IS NULL OR last_synced_at < ?", time.Now().Add(-s.opts.MaxAge)).
+		Order("last_synced_at, id").Limit(s.opts.BatchSize).Find(&games).Error; err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for i, game := range games {
+		if i > 0 {
+			select {
+			case <-time.After(s.opts.Pace):
+			case <-ctx.Done():
+				return synced, ctx.Err()
+			}
+		}
+
+		_, err := s.SyncGame(ctx, game.ID)
+		switch {
+		case err == nil:
+			synced++
+		case errors.Is(err, rawg.ErrRateLimited), errors.Is(err, context.Canceled):
+			return synced, err
+		default:
+			if errors.Is(err, rawg.ErrNotFound) {
+				log.Printf("game %d (RAWG %d) no longer exists on RAWG", game.ID, game.RAWGId)
+			} else {
+				log.Printf("failed to sync game %d (RAWG %d): %v", game.ID, game.RAWGId, err)
+			}
+			if err := s.db.Model(&game).UpdateColumn("last_synced_at", time.Now()).Error; err != nil {
+				return synced, err
+			}
+		}
+	}
+	return synced, nil
+}
+
+// SyncGame refreshes one imported game from RAWG and returns it with its tags and platforms
+func (s *Syncer) SyncGame(ctx context.Context, gameID uint) (*models.Game, error) {
+	var game models.Game
+	if err := s.db.First(&game, gameID).Error; err != nil {
+		return nil, err
+	}
+	if game.IsLocal || game.RAWGId == 0 {
+		return nil, ErrLocalGame
+	}
+
+	data, err := s.client.GetGame(ctx, strconv.Itoa(game.RAWGId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return Apply(tx, &game, data)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Preload("Tags").Preload("Platforms").First(&game, game.ID).Error; err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// Apply copies RAWG metadata onto game, replaces its tags with the RAWG tags and genres
+// and its platforms with the RAWG platforms, and saves it, creating the game if it is new.
+// The title and slug are only set on creation so links to existing games keep working.
+func Apply(tx *gorm.DB, game *models.Game, data *models.RAWGGame) error {
+	now := time.Now()
+	game.Description = data.Description
+	game.CoverImage = data.BackgroundImage
+	game.Released = data.Released
+	game.Rating = data.Rating
+	game.Metacritic = data.Metacritic
+	game.Playtime = data.Playtime
+	game.LastSyncedAt = &now
+
+	tags := make([]models.Tag, 0, len(data.Tags)+len(data.Genres))
+	for _, list := range [][]models.RAWGTag{data.Tags, data.Genres} {
+		for _, rawgTag := range list {
+			tag, err := FindOrCreateTag(tx, rawgTag.Name, rawgTag.Slug)
+			if err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+	}
+
+	platforms := make([]models.Platform, 0, len(data.Platforms))
+	for _, entry := range data.Platforms {
+		platform, err := FindOrCreatePlatform(tx, entry.Platform.Name, entry.Platform.Slug)
+		if err != nil {
+			return err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	if game.ID == 0 {
+		game.RAWGId = data.ID
+		game.Title = data.Name
+		game.Slug = data.Slug
+		game.Tags = tags
+		game.Platforms = platforms
+		return tx.Create(game).Error
+	}
+
+	if err := tx.Omit(clause.Associations).Save(game).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(game).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	return tx.Model(game).Association("Platforms").Replace(platforms)
+}
+
+// FindOrCreateTag returns the tag with this name or slug, creating it if there is none.
+// Both are unique, and a tag created locally may carry a different slug than RAWG's.
+func FindOrCreateTag(tx *gorm.DB, name, slug string) (models.Tag, error) {
+	var tag models.Tag
+	err := tx.Where("LOWER(name) = LOWER(?) OR slug = ?", name, slug).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tag = models.Tag{Name: name, Slug: slug}
+		err = tx.Create(&tag).Error
+	}
+	return tag, err
+}
+
+// FindOrCreatePlatform returns the platform with this name or slug, creating it if there is none
+func FindOrCreatePlatform(tx *gorm.DB, name, slug string) (models.Platform, error) {
+	var platform models.Platform
+	err := tx.Where("LOWER(name) = LOWER(?) OR slug = ?", name, slug).First(&platform).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		platform = models.Platform{Name: name, Slug: slug}
+		err = tx.Create(&platform).Error
+	}
+	return platform, err
+}
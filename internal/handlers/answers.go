@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AcceptAnswer marks a comment as the accepted answer of its post, replacing any earlier one.
+// Only the post author or a moderator may do this.
+func (h *CommentHandler) AcceptAnswer(c *gin.Context) {
+	comment, post, ok := h.loadAnswer(c)
+	if !ok {
+		return
+	}
+	if comment.IsDeleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "deleted comments cannot be accepted"})
+		return
+	}
+
+	h.setAcceptedAnswer(c, post, comment.ID, true)
+}
+
+// UnacceptAnswer clears the post's accepted answer if it is this comment
+func (h *CommentHandler) UnacceptAnswer(c *gin.Context) {
+	comment, post, ok := h.loadAnswer(c)
+	if !ok {
+		return
+	}
+	if post.AcceptedCommentID == nil || *post.AcceptedCommentID != comment.ID {
+		c.JSON(http.StatusOK, gin.H{"post_id": post.ID, "accepted_comment_id": post.AcceptedCommentID})
+		return
+	}
+
+	h.setAcceptedAnswer(c, post, comment.ID, false)
+}
+
+// loadAnswer fetches the comment and its post and checks the user may choose the post's answer
+func (h *CommentHandler) loadAnswer(c *gin.Context) (*models.Comment, *models.Post, bool) {
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return nil, nil, false
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, comment.PostID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return nil, nil, false
+	}
+
+	if post.UserID != c.GetUint("user_id") && !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the post author or a moderator can choose the accepted answer"})
+		return nil, nil, false
+	}
+
+	return &comment, &post, true
+}
+
+// setAcceptedAnswer accepts or unaccepts commentID as the post's answer; a moderator acting
+// on another user's post is audited
+func (h *CommentHandler) setAcceptedAnswer(c *gin.Context, post *models.Post, commentID uint, accept bool) {
+	userID := c.GetUint("user_id")
+
+	var accepted *uint
+	action := "unaccept_answer"
+	if accept {
+		accepted = &commentID
+		action = "accept_answer"
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).UpdateColumn("accepted_comment_id", accepted).Error; err != nil {
+			return err
+		}
+		if post.UserID == userID {
+			return nil
+		}
+		return recordModerationAction(tx, userID, action, "comment", commentID, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update accepted answer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post_id": post.ID, "accepted_comment_id": accepted})
+}
+
+// filterAnswered applies the ?answered=true|false filter on whether posts have an accepted answer
+func filterAnswered(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	raw := c.Query("answered")
+	if raw == "" {
+		return query, nil
+	}
+
+	answered, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("answered must be true or false")
+	}
+	if answered {
+		return query.Where("posts.accepted_comment_id IS NOT NULL"), nil
+	}
+	return query.Where("posts.accepted_comment_id IS NULL"), nil
+}
+
+// attachAcceptedAnswers sets the accepted answer, with its author, on each post that has one
+func attachAcceptedAnswers(db *gorm.DB, posts []models.Post) {
+	var ids []uint
+	for _, post := range posts {
+		if post.AcceptedCommentID != nil {
+			ids = append(ids, *post.AcceptedCommentID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var answers []models.Comment
+	db.Preload("User").Where("id IN ?", ids).Find(&answers)
+
+	byID := make(map[uint]*models.Comment, len(answers))
+	for i := range answers {
+		answers[i].IsAccepted = true
+		byID[answers[i].ID] = &answers[i]
+	}
+	for i := range posts {
+		if posts[i].AcceptedCommentID != nil {
+			posts[i].AcceptedAnswer = byID[*posts[i].AcceptedCommentID]
+		}
+	}
+}
+
+// markAcceptedAnswer flags the post's accepted answer among comments and all their loaded replies
+func markAcceptedAnswer(db *gorm.DB, postID uint, comments []models.Comment) {
+	var post models.Post
+	if err := db.Select("id", "accepted_comment_id").First(&post, postID).Error; err != nil || post.AcceptedCommentID == nil {
+		return
+	}
+
+	for _, comment := range flattenComments(comments) {
+		comment.IsAccepted = comment.ID == *post.AcceptedCommentID
+	}
+}
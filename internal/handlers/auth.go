@@ -117,6 +117,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "your account is banned"})
+		return
+	}
+
 	token, err := middleware.GenerateJWT(user.ID, user.Username, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultCommentDepth is how many reply levels are returned below the requested comments
+	defaultCommentDepth = 5
+	// maxCommentDepth caps the depth a client may request
+	maxCommentDepth = 20
+	// defaultReplyLimit is how many replies are returned per comment before "load more"
+	defaultReplyLimit = 10
+	// maxReplyLimit caps the replies per comment a client may request
+	maxReplyLimit = 50
+)
+
+// controversyExpr ranks comments with many, evenly split votes highest
+const controversyExpr = "CASE WHEN comments.upvotes = 0 OR comments.downvotes = 0 THEN 0 " +
+	"ELSE (comments.upvotes + comments.downvotes) * MIN(comments.upvotes, comments.downvotes) * 1.0 / MAX(comments.upvotes, comments.downvotes) END"
+
+// commentSort orders sibling comments by an optional rank, then by creation time
+type commentSort struct {
+	name        string
+	rank        string                          // SQL expression ordered ascending; empty for pure time order
+	of          func(c *models.Comment) float64 // Computes rank for a loaded comment
+	oldestFirst bool
+}
+
+// commentSorts are the supported ?sort= modes; ranks are negated so higher scores come first
+var commentSorts = map[string]commentSort{
+	"new": {name: "new"},
+	"old": {name: "old", oldestFirst: true},
+	"top": {
+		name: "top",
+		rank: "-comments.score",
+		of:   func(c *models.Comment) float64 { return -float64(c.Score) },
+	},
+	"best": {
+		name: "best",
+		rank: "-comments.best_score",
+		of:   func(c *models.Comment) float64 { return -c.BestScore },
+	},
+	"controversial": {
+		name: "controversial",
+		rank: "-(" + controversyExpr + ")",
+		of: func(c *models.Comment) float64 {
+			if c.Upvotes == 0 || c.Downvotes == 0 {
+				return 0
+			}
+			return -float64((c.Upvotes+c.Downvotes)*min(c.Upvotes, c.Downvotes)) / float64(max(c.Upvotes, c.Downvotes))
+		},
+	},
+}
+
+// orderBy returns the SQL ordering of the sort
+func (s commentSort) orderBy() string {
+	dir := " DESC"
+	if s.oldestFirst {
+		dir = " ASC"
+	}
+	order := "comments.created_at" + dir + ", comments.id" + dir
+	if s.rank != "" {
+		order = s.rank + " ASC, " + order
+	}
+	return order
+}
+
+// less reports whether x sorts before y
+func (s commentSort) less(x, y *models.Comment) bool {
+	if s.of != nil {
+		if rx, ry := s.of(x), s.of(y); rx != ry {
+			return rx < ry
+		}
+	}
+	if !x.CreatedAt.Equal(y.CreatedAt) {
+		return x.CreatedAt.Before(y.CreatedAt) == s.oldestFirst
+	}
+	return (x.ID < y.ID) == s.oldestFirst
+}
+
+// cursor returns the pagination position of a comment under this sort
+func (s commentSort) cursor(c *models.Comment) pageCursor {
+	pc := pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	if s.of != nil {
+		pc.Rank = s.of(c)
+	}
+	return pc
+}
+
+// treeOptions controls how much of a comment tree is loaded
+type treeOptions struct {
+	sort       commentSort
+	depth      int // Reply levels below the roots
+	replyLimit int // Replies per comment
+}
+
+// parseTreeOptions reads sort, depth and replies from the query string
+func parseTreeOptions(c *gin.Context) (treeOptions, error) {
+	opts := treeOptions{
+		sort:       commentSorts["new"],
+		depth:      clampQuery(c, "depth", defaultCommentDepth, 0, maxCommentDepth),
+		replyLimit: clampQuery(c, "replies", defaultReplyLimit, 1, maxReplyLimit),
+	}
+	if name := c.Query("sort"); name != "" {
+		s, ok := commentSorts[name]
+		if !ok {
+			return opts, fmt.Errorf("sort must be one of best, top, new, old or controversial")
+		}
+		opts.sort = s
+	}
+	return opts, nil
+}
+
+// clampQuery reads an integer query parameter, falling back to def and capping at maxValue
+func clampQuery(c *gin.Context, key string, def, minValue, maxValue int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil || value < minValue {
+		return def
+	}
+	return min(value, maxValue)
+}
+
+// loadCommentTrees loads the given root comments of a post and their replies through a
+// recursive CTE. Each comment keeps at most replyLimit replies, ranked by the sort, and
+// replies stop depth levels below the roots.
+func loadCommentTrees(db *gorm.DB, postID uint, rootIDs []uint, opts treeOptions) ([]models.Comment, error) {
+	if len(rootIDs) == 0 {
+		return []models.Comment{}, nil
+	}
+
+	tree := "WITH RECURSIVE ranked(id, parent_id, rn) AS (" +
+		"SELECT id, parent_id, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY " + opts.sort.orderBy() + ") " +
+		"FROM comments WHERE post_id = ? AND parent_id IS NOT NULL" +
+		"), tree(id, depth) AS (" +
+		"SELECT id, 0 FROM comments WHERE id IN ? " +
+		"UNION ALL SELECT ranked.id, tree.depth + 1 FROM ranked JOIN tree ON ranked.parent_id = tree.id " +
+		"WHERE tree.depth < ? AND ranked.rn <= ?" +
+		") SELECT id, depth FROM tree"
+
+	var comments []models.Comment
+	if err := db.Model(&models.Comment{}).
+		Select("comments.*, tree.depth").
+		Joins("JOIN ("+tree+") AS tree ON tree.id = comments.id", postID, rootIDs, opts.depth, opts.replyLimit).
+		Preload("User").
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	return buildCommentTrees(comments, rootIDs, opts), nil
+}
+
+// buildCommentTrees assembles a flat list of comments into trees, keeping the roots in
+// the given order and sorting replies. Comments whose replies were not all loaded are
+// marked with HasMoreReplies and a URL to continue from.
+func buildCommentTrees(comments []models.Comment, rootIDs []uint, opts treeOptions) []models.Comment {
+	byID := make(map[uint]int, len(comments))
+	children := make(map[uint][]int)
+	for i := range comments {
+		byID[comments[i].ID] = i
+		if comments[i].Depth > 0 && comments[i].ParentID != nil {
+			children[*comments[i].ParentID] = append(children[*comments[i].ParentID], i)
+		}
+	}
+
+	var build func(i int) models.Comment
+	build = func(i int) models.Comment {
+		comment := comments[i]
+
+		replies := children[comment.ID]
+		sort.SliceStable(replies, func(a, b int) bool {
+			return opts.sort.less(&comments[replies[a]], &comments[replies[b]])
+		})
+		comment.Replies = make([]models.Comment, 0, len(replies))
+		for _, r := range replies {
+			comment.Replies = append(comment.Replies, build(r))
+		}
+
+		if len(comment.Replies) < comment.ReplyCount {
+			comment.HasMoreReplies = true
+			query := url.Values{"sort": {opts.sort.name}}
+			if len(comment.Replies) == 0 {
+				comment.ContinueThreadURL = fmt.Sprintf("/api/comments/%d?%s", comment.ID, query.Encode())
+			} else {
+				query.Set("cursor", opts.sort.cursor(&comment.Replies[len(comment.Replies)-1]).encode())
+				comment.MoreRepliesURL = fmt.Sprintf("/api/comments/%d/replies?%s", comment.ID, query.Encode())
+			}
+		}
+		return comment
+	}
+
+	trees := make([]models.Comment, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		if i, ok := byID[id]; ok {
+			trees = append(trees, build(i))
+		}
+	}
+	return trees
+}
+
+// listCommentTrees paginates the comments matched by query under the requested sort and
+// writes them as trees under key. A pinned comment is left out of the pages and returned
+// ahead of the first one instead.
+func listCommentTrees(c *gin.Context, db *gorm.DB, postID uint, query *gorm.DB, key string, pinnedID *uint) {
+	opts, err := parseTreeOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination, err := parsePagination(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pagination.OldestFirst = opts.sort.oldestFirst
+	if pinnedID != nil {
+		query = query.Where("comments.id <> ?", *pinnedID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count comments"})
+		return
+	}
+
+	var page []models.Comment
+	if err := pagination.Apply(query, "comments", opts.sort.rank).Find(&page).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comments"})
+		return
+	}
+	page, block := paginate(pagination, page, total, opts.sort.cursor)
+
+	rootIDs := make([]uint, len(page))
+	for i := range page {
+		rootIDs[i] = page[i].ID
+	}
+	trees, err := loadCommentTrees(db, postID, rootIDs, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comments"})
+		return
+	}
+
+	// The pinned tree is loaded on its own since it may be a reply within the page
+	if pinnedID != nil && pagination.cursor == nil && pagination.Page == 1 {
+		pinned, err := loadCommentTrees(db, postID, []uint{*pinnedID}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comments"})
+			return
+		}
+		markPinnedAbove(trees, *pinnedID)
+		trees = append(pinned, trees...)
+	}
+
+	decorateComments(db, c, postID, trees)
+
+	c.JSON(http.StatusOK, gin.H{
+		key:          trees,
+		"pagination": block,
+	})
+}
+
+// markPinnedAbove turns the copy of the pinned comment nested in trees into a reference
+// without replies, since the full tree is already shown first
+func markPinnedAbove(trees []models.Comment, pinnedID uint) {
+	for i := range trees {
+		comment := &trees[i]
+		if comment.ID == pinnedID {
+			comment.PinnedAbove = true
+			comment.Replies = nil
+			comment.HasMoreReplies = false
+			comment.ContinueThreadURL, comment.MoreRepliesURL = "", ""
+			continue
+		}
+		markPinnedAbove(comment.Replies, pinnedID)
+	}
+}
+
+// decorateComments adds the reactions, the viewer's votes, mentions and the accepted-answer
+// flag to comments of a post and all their loaded replies
+func decorateComments(db *gorm.DB, c *gin.Context, postID uint, comments []models.Comment) {
+	attachCommentReactions(db, c, comments)
+	attachCommentVotes(db, c, comments)
+	attachCommentMentions(db, comments)
+	markAcceptedAnswer(db, postID, comments)
+}
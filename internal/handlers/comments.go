@@ -3,7 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"forumapp/internal/config"
+	"forumapp/internal/events"
 	"forumapp/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,70 +15,71 @@ import (
 
 // CommentHandler handles comment-related requests
 type CommentHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	events *events.Bus
 }
 
 // NewCommentHandler creates a new CommentHandler
-func NewCommentHandler(db *gorm.DB) *CommentHandler {
-	return &CommentHandler{db: db}
+func NewCommentHandler(db *gorm.DB, cfg *config.Config, bus *events.Bus) *CommentHandler {
+	return &CommentHandler{db: db, cfg: cfg, events: bus}
 }
 
-// GetCommentsByPost returns all comments for a post with nested replies
+// GetCommentsByPost returns a page of a post's top-level comments with their nested replies.
+// Supports ?sort= (best, top, new, old, controversial), cursor pagination, ?depth= reply
+// levels and ?replies= per comment; cut-off replies are flagged with has_more_replies.
+// The accepted answer, if any, is pinned ahead of the first page.
 func (h *CommentHandler) GetCommentsByPost(c *gin.Context) {
-	postID := c.Param("post_id")
-	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post_id is required"})
+	var post models.Post
+	if err := h.db.Select("id", "accepted_comment_id").First(&post, c.Param("post_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
 		return
 	}
 
-	// Get only top-level comments (ParentID is null)
-	var comments []models.Comment
-	if err := h.db.Where("post_id = ? AND parent_id IS NULL", postID).
-		Preload("User").
-		Preload("Replies").
-		Preload("Replies.User").
-		Preload("Replies.Replies").
-		Preload("Replies.Replies.User").
-		Preload("Replies.Replies.Replies").
-		Preload("Replies.Replies.Replies.User").
-		Order("created_at DESC").
-		Find(&comments).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comments"})
+	query := h.db.Model(&models.Comment{}).Where("comments.post_id = ? AND comments.parent_id IS NULL", post.ID)
+	listCommentTrees(c, h.db, post.ID, query, "comments", post.AcceptedCommentID)
+}
+
+// GetCommentReplies returns a page of a comment's direct replies with their nested replies,
+// for loading more of a subtree
+func (h *CommentHandler) GetCommentReplies(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.Select("id", "post_id").First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, comments)
+	query := h.db.Model(&models.Comment{}).Where("comments.parent_id = ?", comment.ID)
+	listCommentTrees(c, h.db, comment.PostID, query, "replies", nil)
 }
 
-// GetCommentThread returns a specific comment with all its nested replies
+// GetCommentThread returns a specific comment with its nested replies, up to ?depth= levels deep
 func (h *CommentHandler) GetCommentThread(c *gin.Context) {
-	commentID := c.Param("id")
-	if commentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "comment id is required"})
+	var comment models.Comment
+	if err := h.db.Select("id", "post_id").First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
 		return
 	}
 
-	var comment models.Comment
-	if err := h.db.Where("id = ?", commentID).
-		Preload("User").
-		Preload("Replies").
-		Preload("Replies.User").
-		Preload("Replies.Replies").
-		Preload("Replies.Replies.User").
-		Preload("Replies.Replies.Replies").
-		Preload("Replies.Replies.Replies.User").
-		First(&comment).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+	opts, err := parseTreeOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thread, err := loadCommentTrees(h.db, comment.PostID, []uint{comment.ID}, opts)
+	if err != nil || len(thread) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comment"})
 		return
 	}
 
-	c.JSON(http.StatusOK, comment)
+	decorateComments(h.db, c, comment.PostID, thread)
+	c.JSON(http.StatusOK, thread[0])
 }
 
 // CreateComment creates a new comment or reply
 func (h *CommentHandler) CreateComment(c *gin.Context) {
 	userID := c.GetUint("user_id")
-
 	var req models.CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -89,6 +93,11 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
+	if post.IsLocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this post is locked and no longer accepts comments"})
+		return
+	}
+
 	// If this is a reply, verify parent comment exists and belongs to same post
 	if req.ParentID != nil {
 		var parentComment models.Comment
@@ -100,6 +109,10 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "parent comment does not belong to this post"})
 			return
 		}
+		if parentComment.IsDeleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot reply to a deleted comment"})
+			return
+		}
 	}
 
 	comment := models.Comment{
@@ -109,14 +122,50 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		Content:  req.Content,
 	}
 
-	if err := h.db.Create(&comment).Error; err != nil {
+	// Create the comment, its mentions and bump the denormalized counters together.
+	// Bumping the post first takes SQLite's write lock, so concurrent comments are
+	// throttled one at a time against each other's inserts.
+	var mentioned []models.Mention
+	var throttle *commentThrottle
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error; err != nil {
+			return err
+		}
+		var err error
+		if throttle, err = h.checkCommentThrottle(c, tx, userID, &post); err != nil {
+			return err
+		}
+		if throttle != nil {
+			return errCommentThrottled
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID != nil {
+			if err := tx.Model(&models.Comment{}).Where("id = ?", *comment.ParentID).
+				UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error; err != nil {
+				return err
+			}
+		}
+		mentioned, err = syncMentions(tx, userID, "comment", comment.ID, comment.PostID, comment.Content)
+		return err
+	})
+	if throttle != nil {
+		respondThrottled(c, throttle)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create comment"})
 		return
 	}
+	publishMentions(h.events, mentioned)
 
 	// Load user for response
 	h.db.Preload("User").First(&comment, comment.ID)
-	c.JSON(http.StatusCreated, comment)
+	created := []models.Comment{comment}
+	attachCommentMentions(h.db, created)
+	c.JSON(http.StatusCreated, created[0])
 }
 
 // UpdateComment updates a comment (only by the author)
@@ -135,6 +184,10 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "you can only edit your own comments"})
 		return
 	}
+	if comment.IsDeleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "deleted comments cannot be edited"})
+		return
+	}
 
 	var req struct {
 		Content string `json:"content" binding:"required"`
@@ -144,17 +197,30 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	comment.Content = req.Content
-	if err := h.db.Save(&comment).Error; err != nil {
+	// Only touch the content so concurrent counter updates are not overwritten
+	var mentioned []models.Mention
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Update("content", req.Content).Error; err != nil {
+			return err
+		}
+		var err error
+		mentioned, err = syncMentions(tx, userID, "comment", comment.ID, comment.PostID, req.Content)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update comment"})
 		return
 	}
+	publishMentions(h.events, mentioned)
 
 	h.db.Preload("User").First(&comment, comment.ID)
-	c.JSON(http.StatusOK, comment)
+	updated := []models.Comment{comment}
+	attachCommentMentions(h.db, updated)
+	c.JSON(http.StatusOK, updated[0])
 }
 
-// DeleteComment deletes a comment (by the author, moderators, or owners)
+// DeleteComment deletes a comment (by the author, moderators, or owners).
+// Comments with replies become tombstones so the discussion below them survives.
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	role := c.GetString("role")
@@ -172,25 +238,126 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	// Delete all replies recursively
-	h.deleteCommentAndReplies(comment.ID)
+	removal := commentRemoval{byID: userID, byModerator: comment.UserID != userID}
+	if removal.byModerator {
+		removal.reason = bindReason(c)
+	}
+
+	tombstoned, err := deleteComment(h.db, &comment, removal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted", "tombstoned": tombstoned})
+}
+
+const (
+	// deletedCommentContent replaces the content of a comment its author deleted
+	deletedCommentContent = "[deleted]"
+	// removedCommentContent replaces the content of a comment a moderator removed
+	removedCommentContent = "[removed by moderator]"
+)
 
-	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+// commentRemoval describes who is deleting a comment and why
+type commentRemoval struct {
+	byID        uint
+	byModerator bool
+	reason      string
 }
 
-// deleteCommentAndReplies recursively deletes a comment and all its replies
-func (h *CommentHandler) deleteCommentAndReplies(commentID uint) {
-	// Find all direct replies
-	var replies []models.Comment
-	h.db.Where("parent_id = ?", commentID).Find(&replies)
+// deleteComment deletes a comment in one transaction. A comment with replies is turned
+// into a tombstone; a leaf comment is purged along with any tombstoned ancestors it
+// leaves without replies. Removals by moderators are audited in the same transaction.
+// It reports whether a tombstone was left behind.
+func deleteComment(db *gorm.DB, comment *models.Comment, removal commentRemoval) (bool, error) {
+	tombstoned := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var replies int64
+		if err := tx.Model(&models.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			tombstoned = true
+			if err := tombstoneComment(tx, comment, removal); err != nil {
+				return err
+			}
+		} else if err := purgeComment(tx, comment); err != nil {
+			return err
+		}
+		if removal.byModerator {
+			return recordModerationAction(tx, removal.byID, "delete", "comment", comment.ID, removal.reason)
+		}
+		return nil
+	})
+	return tombstoned, err
+}
 
-	// Recursively delete replies
-	for _, reply := range replies {
-		h.deleteCommentAndReplies(reply.ID)
+// tombstoneComment blanks a comment's content and records who removed it and why
+func tombstoneComment(tx *gorm.DB, comment *models.Comment, removal commentRemoval) error {
+	content := deletedCommentContent
+	if removal.byModerator {
+		content = removedCommentContent
 	}
 
-	// Delete the comment itself
-	h.db.Delete(&models.Comment{}, commentID)
+	if err := tx.Model(comment).Updates(map[string]interface{}{
+		"content":        content,
+		"is_deleted":     true,
+		"removed_by_id":  removal.byID,
+		"removal_reason": removal.reason,
+		"removed_at":     time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	return dropCommentExtras(tx, comment.ID)
+}
+
+// purgeComment hard-deletes a leaf comment and updates the counters, then purges the
+// parent too if it is a tombstone that no longer has replies
+func purgeComment(tx *gorm.DB, comment *models.Comment) error {
+	for {
+		if err := dropCommentExtras(tx, comment.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Comment{}, comment.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comment_count", gorm.Expr("MAX(comment_count - 1, 0)")).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+
+		if err := tx.Model(&models.Comment{}).Where("id = ?", *comment.ParentID).
+			UpdateColumn("reply_count", gorm.Expr("MAX(reply_count - 1, 0)")).Error; err != nil {
+			return err
+		}
+		var parent models.Comment
+		if err := tx.First(&parent, *comment.ParentID).Error; err != nil {
+			return err
+		}
+		if !parent.IsDeleted || parent.ReplyCount > 0 {
+			return nil
+		}
+		comment = &parent
+	}
+}
+
+// dropCommentExtras removes the bookmarks of, reactions to and mentions in a comment,
+// and unmarks it as its post's accepted answer
+func dropCommentExtras(tx *gorm.DB, commentID uint) error {
+	for _, model := range []interface{}{&models.SavedItem{}, &models.Reaction{}, &models.Mention{}} {
+		if err := tx.Where("target_type = ? AND target_id = ?", "comment", commentID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Post{}).Where("accepted_comment_id = ?", commentID).
+		UpdateColumn("accepted_comment_id", nil).Error
 }
 
 // GetCommentCount returns the total number of comments for a post
@@ -201,13 +368,13 @@ func (h *CommentHandler) GetCommentCount(c *gin.Context) {
 		return
 	}
 
-	var count int64
-	if err := h.db.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&count).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count comments"})
+	var post models.Post
+	if err := h.db.Select("id", "comment_count").First(&post, postID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"count": count})
+	c.JSON(http.StatusOK, gin.H{"count": post.CommentCount})
 }
 
 // GetRecentComments returns recent comments across all posts
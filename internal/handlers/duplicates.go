@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"forumapp/internal/models"
+	"forumapp/internal/preview"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// duplicateWindow is how far back posts are compared for duplicates
+	duplicateWindow = 7 * 24 * time.Hour
+	// duplicateScanLimit caps how many recent posts of a game are compared
+	duplicateScanLimit = 200
+	// duplicateThreshold is the similarity from which a post counts as a likely duplicate
+	duplicateThreshold = 0.6
+	// maxDuplicateCandidates caps how many likely duplicates are returned
+	maxDuplicateCandidates = 5
+)
+
+// stopWords are ignored when comparing posts so "is the server down?" matches "server down"
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "was": true, "be": true,
+	"to": true, "of": true, "and": true, "or": true, "in": true, "on": true, "at": true,
+	"it": true, "its": true, "for": true, "with": true, "i": true, "my": true, "me": true,
+	"anyone": true, "else": true, "just": true, "this": true, "that": true, "so": true,
+}
+
+// CheckDuplicates returns likely duplicates of a draft post so the composer can warn before posting
+func (h *PostHandler) CheckDuplicates(c *gin.Context) {
+	var req struct {
+		GameID  uint   `json:"game_id" binding:"required"`
+		Title   string `json:"title" binding:"required"`
+		Content string `json:"content"`
+		LinkURL string `json:"link_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.LinkURL != "" {
+		if normalized, err := preview.ValidateURL(req.LinkURL); err == nil {
+			req.LinkURL = normalized
+		}
+	}
+
+	duplicates, err := h.findDuplicates(req.GameID, req.Title, req.Content, req.LinkURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for duplicates"})
+		return
+	}
+
+	if duplicates == nil {
+		duplicates = []models.DuplicateCandidate{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"duplicates":         duplicates,
+		"blocks_exact_match": h.cfg.DuplicatePostMode == "block",
+	})
+}
+
+// findDuplicates compares a draft against recent posts of the same game
+func (h *PostHandler) findDuplicates(gameID uint, title, content, linkURL string) ([]models.DuplicateCandidate, error) {
+	if h.cfg.DuplicatePostMode == "off" {
+		return nil, nil
+	}
+
+	var recent []models.Post
+	if err := h.db.Select("id", "title", "content", "link_url", "created_at", "comment_count").
+		Where("posts.id IN (SELECT post_id FROM post_games WHERE game_id = ?)", gameID).
+		Where("created_at >= ?", time.Now().Add(-duplicateWindow)).
+		Order("created_at DESC").
+		Limit(duplicateScanLimit).
+		Find(&recent).Error; err != nil {
+		return nil, err
+	}
+
+	draftTitle := normalizeText(title)
+	draftContent := normalizeText(content)
+	titleTokens := tokenSet(draftTitle)
+	contentTokens := tokenSet(draftContent)
+
+	var candidates []models.DuplicateCandidate
+	for _, post := range recent {
+		postTitle := normalizeText(post.Title)
+		postContent := normalizeText(post.Content)
+
+		exact := postTitle == draftTitle &&
+			(postContent == draftContent || (linkURL != "" && post.LinkURL == linkURL))
+
+		// Title-only drafts are compared on the title alone
+		similarity := jaccard(titleTokens, tokenSet(postTitle))
+		if len(contentTokens) > 0 {
+			similarity = 0.7*similarity + 0.3*jaccard(contentTokens, tokenSet(postContent))
+		}
+		if exact {
+			similarity = 1
+		}
+		if similarity < duplicateThreshold {
+			continue
+		}
+
+		candidates = append(candidates, models.DuplicateCandidate{
+			ID:           post.ID,
+			Title:        post.Title,
+			CreatedAt:    post.CreatedAt,
+			CommentCount: post.CommentCount,
+			Similarity:   float64(int(similarity*100)) / 100,
+			Exact:        exact,
+		})
+	}
+
+	// Exact matches go first among equally similar posts so the cap never drops them
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Similarity != candidates[j].Similarity {
+			return candidates[i].Similarity > candidates[j].Similarity
+		}
+		return candidates[i].Exact && !candidates[j].Exact
+	})
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+	return candidates, nil
+}
+
+// hasExactDuplicate reports whether any candidate is an exact duplicate
+func hasExactDuplicate(candidates []models.DuplicateCandidate) bool {
+	for _, candidate := range candidates {
+		if candidate.Exact {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeText lowercases text, drops punctuation and collapses whitespace
+func normalizeText(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// tokenSet returns the distinct meaningful words of normalized text
+func tokenSet(normalized string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range strings.Fields(normalized) {
+		if stopWords[word] {
+			continue
+		}
+		// Crude plural folding so "servers" matches "server"
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two word sets; two empty sets are identical
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for word := range a {
+		if _, ok := b[word]; ok {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
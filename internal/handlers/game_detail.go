@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GameStats summarizes the activity in the posts linked to a game
+type GameStats struct {
+	PostCount        int64             `json:"post_count"`
+	CommentCount     int64             `json:"comment_count"`
+	LatestActivityAt *time.Time        `json:"latest_activity_at"` // Newest post or comment; nil without any
+	TopContributors  []GameContributor `json:"top_contributors"`
+}
+
+// GameContributor is a user ranked by how much they posted and commented about a game
+type GameContributor struct {
+	User         models.User `json:"user"`
+	PostCount    int64       `json:"post_count"`
+	CommentCount int64       `json:"comment_count"`
+}
+
+// topContributorLimit is how many contributors the game detail lists
+const topContributorLimit = 5
+
+// gamePostIDs selects the posts linked to a game, the primary one included
+const gamePostIDs = "SELECT post_id FROM post_games WHERE game_id = ?"
+
+// gameContributions lists one row per post and live comment in a game's threads
+const gameContributions = "SELECT user_id, 'post' AS kind FROM posts WHERE id IN (" + gamePostIDs + ") " +
+	"UNION ALL SELECT user_id, 'comment' AS kind FROM comments WHERE post_id IN (" + gamePostIDs + ") AND is_deleted = false"
+
+// GetLocalGameByID returns a game's detail page by ID
+func (h *GameHandler) GetLocalGameByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+	h.gameDetail(c, h.db.Where("id = ?", id))
+}
+
+// GetLocalGameBySlug returns a game's detail page by slug
+func (h *GameHandler) GetLocalGameBySlug(c *gin.Context) {
+	h.gameDetail(c, h.db.Where("slug = ?", c.Param("slug")))
+}
+
+// gameDetail writes a game with its tags, platforms, activity stats and a page of its
+// most recent posts
+func (h *GameHandler) gameDetail(c *gin.Context, query *gorm.DB) {
+	pagination, err := parsePagination(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var game models.Game
+	if err := query.Preload("Tags").Preload("Platforms").First(&game).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+
+	stats, err := gameStats(h.db, game.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute game stats"})
+		return
+	}
+
+	var posts []models.Post
+	postQuery := withPostDetails(h.db.Model(&models.Post{})).Where("posts.id IN ("+gamePostIDs+")", game.ID)
+	if err := pagination.Apply(postQuery, "posts", "").Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
+		return
+	}
+
+	posts, page := paginate(pagination, posts, stats.PostCount, func(p *models.Post) pageCursor {
+		return pageCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+	decoratePosts(h.db, c, posts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"game":         game,
+		"stats":        stats,
+		"recent_posts": posts,
+		"pagination":   page,
+	})
+}
+
+// gameStats counts the posts and live comments about a game and ranks its contributors
+func gameStats(db *gorm.DB, gameID uint) (GameStats, error) {
+	stats := GameStats{TopContributors: []GameContributor{}}
+
+	// Each query is reused for a count and the latest activity
+	posts := db.Model(&models.Post{}).Where("id IN ("+gamePostIDs+")", gameID).Session(&gorm.Session{})
+	comments := db.Model(&models.Comment{}).Where("post_id IN ("+gamePostIDs+") AND is_deleted = ?", gameID, false).
+		Session(&gorm.Session{})
+	if err := posts.Count(&stats.PostCount).Error; err != nil {
+		return stats, err
+	}
+	if err := comments.Count(&stats.CommentCount).Error; err != nil {
+		return stats, err
+	}
+
+	var latest []time.Time
+	for _, activity := range []*gorm.DB{posts, comments} {
+		var times []time.Time
+		if err := activity.Order("created_at DESC").Limit(1).Pluck("created_at", &times).Error; err != nil {
+			return stats, err
+		}
+		latest = append(latest, times...)
+	}
+	for i := range latest {
+		if stats.LatestActivityAt == nil || latest[i].After(*stats.LatestActivityAt) {
+			stats.LatestActivityAt = &latest[i]
+		}
+	}
+
+	var ranked []struct {
+		UserID       uint
+		PostCount    int64
+		CommentCount int64
+	}
+	if err := db.Raw("SELECT user_id, "+
+		"SUM(CASE kind WHEN 'post' THEN 1 ELSE 0 END) AS post_count, "+
+		"SUM(CASE kind WHEN 'comment' THEN 1 ELSE 0 END) AS comment_count "+
+		"FROM ("+gameContributions+") GROUP BY user_id ORDER BY COUNT(*) DESC, user_id LIMIT ?",
+		gameID, gameID, topContributorLimit).Scan(&ranked).Error; err != nil {
+		return stats, err
+	}
+	if len(ranked) == 0 {
+		return stats, nil
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.UserID
+	}
+	var users []models.User
+	if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return stats, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	for _, r := range ranked {
+		if user, ok := byID[r.UserID]; ok {
+			stats.TopContributors = append(stats.TopContributors, GameContributor{
+				User:         user,
+				PostCount:    r.PostCount,
+				CommentCount: r.CommentCount,
+			})
+		}
+	}
+	return stats, nil
+}
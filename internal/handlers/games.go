@@ -1,15 +1,17 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
+	"log"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 
 	"forumapp/internal/config"
+	"forumapp/internal/gamesync"
 	"forumapp/internal/models"
+	"forumapp/internal/rawg"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,15 +19,19 @@ import (
 
 // GameHandler handles game-related requests
 type GameHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db     *gorm.DB
+	cfg    *config.Config
+	rawg   rawg.Client
+	syncer *gamesync.Syncer
 }
 
 // NewGameHandler creates a new GameHandler
-func NewGameHandler(db *gorm.DB, cfg *config.Config) *GameHandler {
+func NewGameHandler(db *gorm.DB, cfg *config.Config, client rawg.Client, syncer *gamesync.Syncer) *GameHandler {
 	return &GameHandler{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		cfg:    cfg,
+		rawg:   client,
+		syncer: syncer,
 	}
 }
 
@@ -38,12 +44,12 @@ func (h *GameHandler) SearchRAWGGames(c *gin.Context) {
 		return
 	}
 
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "20")
+	page := clampQuery(c, "page", 1, 1, 500)
+	pageSize := clampQuery(c, "page_size", 20, 1, 40)
 
-	games, err := h.fetchFromRAWG(query, page, pageSize)
+	games, err := h.rawg.SearchGames(c.Request.Context(), query, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search games from RAWG: " + err.Error()})
+		respondRAWGError(c, err, "failed to search games from RAWG")
 		return
 	}
 
@@ -58,26 +64,38 @@ func (h *GameHandler) GetRAWGGameDetails(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://api.rawg.io/api/games/%s?key=%s", gameID, h.cfg.RAWGAPIKey)
-	resp, err := http.Get(url)
+	game, err := h.rawg.GetGame(c.Request.Context(), gameID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch game details"})
+		respondRAWGError(c, err, "failed to fetch game details")
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
-		return
-	}
+	c.JSON(http.StatusOK, game)
+}
 
-	var game models.RAWGGame
-	if err := json.NewDecoder(resp.Body).Decode(&game); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse game details"})
+// respondRAWGError maps a RAWG client error to a response without exposing upstream
+// details, which are logged instead
+func respondRAWGError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, rawg.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found on RAWG"})
+		return
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody is left to answer
+		c.Status(499)
 		return
 	}
 
-	c.JSON(http.StatusOK, game)
+	log.Printf("%s: %v", message, err)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": message + ": RAWG did not respond in time"})
+	case errors.Is(err, rawg.ErrRateLimited):
+		c.Header("Retry-After", "60")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": message + ": RAWG is busy, try again later"})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	}
 }
 
 // CreateLocalGame creates a new game in the local database
@@ -107,33 +125,39 @@ func (h *GameHandler) CreateLocalGame(c *gin.Context) {
 		IsLocal:     true,
 	}
 
-	// Parse and create tags
-	if req.Tags != "" {
-		tagNames := strings.Split(req.Tags, ",")
-		for _, tagName := range tagNames {
-			tagName = strings.TrimSpace(tagName)
-			if tagName == "" {
-				continue
+	// Tags and platforms are created with the game so a failure leaves none behind
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if req.Tags != "" {
+			tagNames := strings.Split(req.Tags, ",")
+			for _, tagName := range tagNames {
+				tagName = strings.TrimSpace(tagName)
+				if tagName == "" {
+					continue
+				}
+				tagSlug := strings.ToLower(strings.ReplaceAll(tagName, " ", "-"))
+
+				tag, err := gamesync.FindOrCreateTag(tx, tagName, tagSlug)
+				if err != nil {
+					return err
+				}
+				game.Tags = append(game.Tags, tag)
 			}
-			tagSlug := strings.ToLower(strings.ReplaceAll(tagName, " ", "-"))
-
-			var tag models.Tag
-			// Find or create tag
-			if err := h.db.Where("slug = ?", tagSlug).First(&tag).Error; err == gorm.ErrRecordNotFound {
-				tag = models.Tag{Name: tagName, Slug: tagSlug}
-				h.db.Create(&tag)
-			}
-			game.Tags = append(game.Tags, tag)
 		}
-	}
 
-	if err := h.db.Create(&game).Error; err != nil {
+		platforms, err := findOrCreatePlatforms(tx, req.Platforms)
+		if err != nil {
+			return err
+		}
+		game.Platforms = platforms
+		return tx.Create(&game).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create game"})
 		return
 	}
 
-	// Reload with tags
-	h.db.Preload("Tags").First(&game, game.ID)
+	// Reload with tags and platforms
+	h.db.Preload("Tags").Preload("Platforms").First(&game, game.ID)
 	c.JSON(http.StatusCreated, game)
 }
 
@@ -146,151 +170,111 @@ func (h *GameHandler) ImportFromRAWG(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "rawg_id is required"})
 		return
 	}
-	rawgID := strconv.Itoa(req.RawgID)
 
-	// Fetch game details from RAWG
-	url := fmt.Sprintf("https://api.rawg.io/api/games/%s?key=%s", rawgID, h.cfg.RAWGAPIKey)
-	resp, err := http.Get(url)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch game from RAWG"})
+	// Check if already imported
+	var existingGame models.Game
+	if err := h.db.Where("rawg_id = ?", req.RawgID).First(&existingGame).Error; err == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "game already imported", "game": existingGame})
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusNotFound, gin.H{"error": "game not found on RAWG"})
+	// Fetch game details from RAWG
+	rawgGame, err := h.rawg.GetGame(c.Request.Context(), strconv.Itoa(req.RawgID))
+	if err != nil {
+		respondRAWGError(c, err, "failed to fetch game from RAWG")
 		return
 	}
 
-	var rawgGame models.RAWGGame
-	if err := json.NewDecoder(resp.Body).Decode(&rawgGame); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse RAWG response"})
+	// Create the local game with its tags, and genres as tags, in one transaction
+	game := models.Game{IsLocal: false}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		return gamesync.Apply(tx, &game, rawgGame)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import game"})
 		return
 	}
 
-	// Check if already imported
-	var existingGame models.Game
-	if err := h.db.Where("rawg_id = ?", rawgGame.ID).First(&existingGame).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{"message": "game already imported", "game": existingGame})
-		return
-	}
+	h.db.Preload("Tags").Preload("Platforms").First(&game, game.ID)
+	c.JSON(http.StatusCreated, game)
+}
 
-	// Create local game from RAWG data
-	game := models.Game{
-		RAWGId:      rawgGame.ID,
-		Title:       rawgGame.Name,
-		Slug:        rawgGame.Slug,
-		Description: rawgGame.Description,
-		CoverImage:  rawgGame.BackgroundImage,
-		Released:    rawgGame.Released,
-		Rating:      rawgGame.Rating,
-		Metacritic:  rawgGame.Metacritic,
-		Playtime:    rawgGame.Playtime,
-		IsLocal:     false,
+// ResyncGame refreshes an imported game's metadata from RAWG right away (moderators only)
+func (h *GameHandler) ResyncGame(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can resync games"})
+		return
 	}
 
-	// Import tags from RAWG
-	for _, rawgTag := range rawgGame.Tags {
-		var tag models.Tag
-		if err := h.db.Where("slug = ?", rawgTag.Slug).First(&tag).Error; err == gorm.ErrRecordNotFound {
-			tag = models.Tag{Name: rawgTag.Name, Slug: rawgTag.Slug}
-			h.db.Create(&tag)
-		}
-		game.Tags = append(game.Tags, tag)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
 	}
 
-	// Also import genres as tags
-	for _, genre := range rawgGame.Genres {
-		var tag models.Tag
-		if err := h.db.Where("slug = ?", genre.Slug).First(&tag).Error; err == gorm.ErrRecordNotFound {
-			tag = models.Tag{Name: genre.Name, Slug: genre.Slug}
-			h.db.Create(&tag)
+	game, err := h.syncer.SyncGame(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+	case errors.Is(err, gamesync.ErrLocalGame):
+		c.JSON(http.StatusConflict, gin.H{"error": "local games are not synced from RAWG"})
+	case err != nil:
+		var rawgErr *rawg.Error
+		if errors.As(err, &rawgErr) {
+			respondRAWGError(c, err, "failed to sync game from RAWG")
+			return
 		}
-		game.Tags = append(game.Tags, tag)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sync game"})
+	default:
+		c.JSON(http.StatusOK, game)
 	}
-
-	if err := h.db.Create(&game).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import game"})
-		return
-	}
-
-	h.db.Preload("Tags").First(&game, game.ID)
-	c.JSON(http.StatusCreated, game)
 }
 
-// GetLocalGames returns all games from the local database
+// GetLocalGames returns all games from the local database, optionally only those on ?platform=
 func (h *GameHandler) GetLocalGames(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 20
+	pagination, err := parsePagination(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	offset := (page - 1) * limit
-
 	var games []models.Game
 	var total int64
 
-	query := h.db.Model(&models.Game{}).Preload("Tags")
+	query := filterGamePlatforms(c, h.db.Model(&models.Game{}).Preload("Tags").Preload("Platforms"))
 
 	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count games"})
 		return
 	}
 
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&games).Error; err != nil {
+	if err := pagination.Apply(query, "games", "").Find(&games).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch games"})
 		return
 	}
 
+	games, page := paginate(pagination, games, total, gameCursor)
+
 	c.JSON(http.StatusOK, gin.H{
-		"games": games,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
+		"games":      games,
+		"pagination": page,
 	})
 }
 
-// GetLocalGameByID returns a specific game from the local database
-func (h *GameHandler) GetLocalGameByID(c *gin.Context) {
-	id := c.Param("id")
-
-	var game models.Game
-	if err := h.db.Preload("Tags").Preload("Posts").Preload("Posts.User").First(&game, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, game)
-}
-
 // GetGamesByTag returns games filtered by tag
 func (h *GameHandler) GetGamesByTag(c *gin.Context) {
-	tagSlug := c.Param("tag")
+	tagSlug := c.Param("tag_slug")
 	if tagSlug == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "tag is required"})
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 20
+	pagination, err := parsePagination(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	offset := (page - 1) * limit
-
 	var tag models.Tag
 	if err := h.db.Where("slug = ?", tagSlug).First(&tag).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
@@ -300,33 +284,36 @@ func (h *GameHandler) GetGamesByTag(c *gin.Context) {
 	var games []models.Game
 	var total int64
 
-	// Count games with this tag
-	h.db.Model(&models.Game{}).
+	query := filterGamePlatforms(c, h.db.Model(&models.Game{}).Preload("Tags").Preload("Platforms").
 		Joins("JOIN game_tags ON game_tags.game_id = games.id").
-		Where("game_tags.tag_id = ?", tag.ID).
-		Count(&total)
+		Where("game_tags.tag_id = ?", tag.ID))
+
+	// Count games with this tag
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count games"})
+		return
+	}
 
 	// Get games with this tag
-	h.db.Preload("Tags").
-		Joins("JOIN game_tags ON game_tags.game_id = games.id").
-		Where("game_tags.tag_id = ?", tag.ID).
-		Order("games.created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&games)
+	if err := pagination.Apply(query, "games", "").Find(&games).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch games"})
+		return
+	}
+
+	games, page := paginate(pagination, games, total, gameCursor)
 
 	c.JSON(http.StatusOK, gin.H{
-		"tag":   tag,
-		"games": games,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
+		"tag":        tag,
+		"games":      games,
+		"pagination": page,
 	})
 }
 
+// gameCursor returns the pagination position of a game
+func gameCursor(g *models.Game) pageCursor {
+	return pageCursor{CreatedAt: g.CreatedAt, ID: g.ID}
+}
+
 // GetAllTags returns all tags
 func (h *GameHandler) GetAllTags(c *gin.Context) {
 	var tags []models.Tag
@@ -336,28 +323,3 @@ func (h *GameHandler) GetAllTags(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, tags)
 }
-
-// fetchFromRAWG fetches games from the RAWG API
-func (h *GameHandler) fetchFromRAWG(query, page, pageSize string) (*models.RAWGSearchResponse, error) {
-	// URL-encode the search query to handle spaces and special characters
-	encodedQuery := url.QueryEscape(query)
-	apiURL := fmt.Sprintf("https://api.rawg.io/api/games?key=%s&search=%s&page=%s&page_size=%s",
-		h.cfg.RAWGAPIKey, encodedQuery, page, pageSize)
-
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("RAWG API returned status %d", resp.StatusCode)
-	}
-
-	var data models.RAWGSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
-	}
-
-	return &data, nil
-}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"forumapp/internal/events"
+	"forumapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxMentions caps how many users a single post or comment can mention
+const maxMentions = 20
+
+// mentionPattern matches @username when not preceded by a word character, so e-mail addresses are skipped
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])(@([\p{L}\p{N}_][\p{L}\p{N}_.-]{0,63}))`)
+
+// parsedMention is an @username found in content, with character offsets of the whole mention
+type parsedMention struct {
+	username   string
+	start, end int
+}
+
+// parseMentions finds the @username mentions in content
+func parseMentions(content string) []parsedMention {
+	var mentions []parsedMention
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		// Trailing punctuation ends a sentence rather than a username
+		name := strings.TrimRight(content[match[4]:match[5]], ".-")
+		start := match[2]
+		end := match[4] + len(name)
+
+		mentions = append(mentions, parsedMention{
+			username: name,
+			start:    utf8.RuneCountInString(content[:start]),
+			end:      utf8.RuneCountInString(content[:end]),
+		})
+		if len(mentions) == maxMentions {
+			break
+		}
+	}
+	return mentions
+}
+
+// syncMentions replaces the mention records of a post or comment with the ones in its
+// content and returns the records of users who were not mentioned there before
+func syncMentions(tx *gorm.DB, authorID uint, targetType string, targetID, postID uint, content string) ([]models.Mention, error) {
+	var previous []uint
+	if err := tx.Model(&models.Mention{}).Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Pluck("mentioned_user_id", &previous).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	parsed := parseMentions(content)
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(parsed))
+	lowered := make([]string, len(parsed))
+	for i, m := range parsed {
+		names[i] = m.username
+		lowered[i] = strings.ToLower(m.username)
+	}
+	// SQLite's LOWER only folds ASCII, so exact spellings are matched as well
+	var users []models.User
+	if err := tx.Select("id", "username").Where("username IN ? OR LOWER(username) IN ?", names, lowered).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	// Prefer an exact match when usernames differ only by case
+	exact := make(map[string]models.User, len(users))
+	folded := make(map[string]models.User, len(users))
+	for _, user := range users {
+		exact[user.Username] = user
+		folded[strings.ToLower(user.Username)] = user
+	}
+
+	var records []models.Mention
+	for _, m := range parsed {
+		user, ok := exact[m.username]
+		if !ok {
+			if user, ok = folded[strings.ToLower(m.username)]; !ok {
+				continue
+			}
+		}
+		records = append(records, models.Mention{
+			MentionedUserID: user.ID,
+			AuthorID:        authorID,
+			TargetType:      targetType,
+			TargetID:        targetID,
+			PostID:          postID,
+			Start:           m.start,
+			End:             m.end,
+		})
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	notified := make(map[uint]bool, len(previous)+1)
+	notified[authorID] = true // Mentioning yourself is not news
+	for _, id := range previous {
+		notified[id] = true
+	}
+
+	var fresh []models.Mention
+	for _, record := range records {
+		if !notified[record.MentionedUserID] {
+			notified[record.MentionedUserID] = true
+			fresh = append(fresh, record)
+		}
+	}
+	return fresh, nil
+}
+
+// publishMentions emits a UserMentioned event for each newly mentioned user
+func publishMentions(bus *events.Bus, mentions []models.Mention) {
+	for _, m := range mentions {
+		bus.Publish(events.UserMentioned{
+			MentionID:       m.ID,
+			MentionedUserID: m.MentionedUserID,
+			AuthorID:        m.AuthorID,
+			TargetType:      m.TargetType,
+			TargetID:        m.TargetID,
+			PostID:          m.PostID,
+			CreatedAt:       m.CreatedAt,
+		})
+	}
+}
+
+// mentionSpans loads the resolved mentions of several targets in one query
+func mentionSpans(db *gorm.DB, targetType string, ids []uint) map[uint][]models.MentionSpan {
+	spans := make(map[uint][]models.MentionSpan)
+	if len(ids) == 0 {
+		return spans
+	}
+
+	var rows []struct {
+		TargetID uint
+		models.MentionSpan
+	}
+	db.Model(&models.Mention{}).
+		Select("mentions.target_id, mentions.mentioned_user_id AS user_id, users.username, mentions.`start`, mentions.`end`").
+		Joins("JOIN users ON users.id = mentions.mentioned_user_id").
+		Where("mentions.target_type = ? AND mentions.target_id IN ?", targetType, ids).
+		Order("mentions.`start` ASC").
+		Scan(&rows)
+
+	for _, row := range rows {
+		spans[row.TargetID] = append(spans[row.TargetID], row.MentionSpan)
+	}
+	return spans
+}
+
+// attachPostMentions sets the resolved mention spans on posts
+func attachPostMentions(db *gorm.DB, posts []models.Post) {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	spans := mentionSpans(db, "post", ids)
+	for i := range posts {
+		posts[i].Mentions = spans[posts[i].ID]
+	}
+}
+
+// attachCommentMentions sets the resolved mention spans on comments and all their loaded replies
+func attachCommentMentions(db *gorm.DB, comments []models.Comment) {
+	all := flattenComments(comments)
+	ids := make([]uint, len(all))
+	for i, comment := range all {
+		ids[i] = comment.ID
+	}
+
+	spans := mentionSpans(db, "comment", ids)
+	for _, comment := range all {
+		comment.Mentions = spans[comment.ID]
+	}
+}
+
+// flattenComments returns pointers to the comments and all their loaded replies
+func flattenComments(comments []models.Comment) []*models.Comment {
+	var all []*models.Comment
+	for i := range comments {
+		all = append(all, &comments[i])
+		all = append(all, flattenComments(comments[i].Replies)...)
+	}
+	return all
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ModerationHandler handles moderator-only thread management requests
+type ModerationHandler struct {
+	db *gorm.DB
+}
+
+// NewModerationHandler creates a new ModerationHandler
+func NewModerationHandler(db *gorm.DB) *ModerationHandler {
+	return &ModerationHandler{db: db}
+}
+
+// isModerator reports whether a role is allowed to perform moderation actions
+func isModerator(role string) bool {
+	return role == "moderator" || role == "owner"
+}
+
+// recordModerationAction stores an audit entry for a moderation action
+func recordModerationAction(tx *gorm.DB, moderatorID uint, action, targetType string, targetID uint, reason string) error {
+	return tx.Create(&models.ModerationAction{
+		ModeratorID: moderatorID,
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Reason:      reason,
+	}).Error
+}
+
+// PinPost pins a post globally or within its game
+func (h *ModerationHandler) PinPost(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can pin posts"})
+		return
+	}
+
+	var req models.PinPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = "game"
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	if req.Scope == "game" && post.GameID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "post has no game to pin within"})
+		return
+	}
+
+	h.updateThread(c, &post, map[string]interface{}{"is_pinned": true, "pin_scope": req.Scope}, "pin", req.Reason)
+}
+
+// UnpinPost removes a post's pin
+func (h *ModerationHandler) UnpinPost(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can unpin posts"})
+		return
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.updateThread(c, &post, map[string]interface{}{"is_pinned": false, "pin_scope": ""}, "unpin", bindReason(c))
+}
+
+// SetSlowMode sets the minimum number of seconds between one user's comments on a post
+func (h *ModerationHandler) SetSlowMode(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can set slow mode"})
+		return
+	}
+
+	var req models.SlowModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.updateThread(c, &post, map[string]interface{}{"slow_mode_seconds": *req.Seconds}, "slow_mode", req.Reason)
+}
+
+// LockPost prevents new comments on a post
+func (h *ModerationHandler) LockPost(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can lock posts"})
+		return
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.updateThread(c, &post, map[string]interface{}{"is_locked": true}, "lock", bindReason(c))
+}
+
+// UnlockPost allows new comments on a locked post again
+func (h *ModerationHandler) UnlockPost(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can unlock posts"})
+		return
+	}
+
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.updateThread(c, &post, map[string]interface{}{"is_locked": false}, "unlock", bindReason(c))
+}
+
+// GetModerationActions returns the most recent moderation actions
+func (h *ModerationHandler) GetModerationActions(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can view the moderation log"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Preload("Moderator").Order("created_at DESC").Limit(limit)
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var actions []models.ModerationAction
+	if err := query.Find(&actions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch moderation actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, actions)
+}
+
+// updateThread applies moderation updates to a post and records the action in one transaction
+func (h *ModerationHandler) updateThread(c *gin.Context, post *models.Post, updates map[string]interface{}, action, reason string) {
+	moderatorID := c.GetUint("user_id")
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordModerationAction(tx, moderatorID, action, "post", post.ID, reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " post"})
+		return
+	}
+
+	withPostDetails(h.db).First(post, post.ID)
+	c.JSON(http.StatusOK, post)
+}
+
+// bindReason reads an optional moderation reason from the request body
+func bindReason(c *gin.Context) string {
+	var req models.ModerationRequest
+	c.ShouldBindJSON(&req)
+	return req.Reason
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidCursor is returned when a client sends a cursor we did not issue
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position of a row in a list ordered by rank, then by creation time.
+// It is serialized as opaque base64 so clients treat it as a token.
+type pageCursor struct {
+	Rank      float64   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+	Backward  bool      `json:"b,omitempty"` // true for prev_cursor tokens
+}
+
+// encode returns the opaque string form of the cursor
+func (pc pageCursor) encode() string {
+	data, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor previously produced by encode
+func decodeCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var pc pageCursor
+	if err := json.Unmarshal(data, &pc); err != nil || pc.ID == 0 {
+		return nil, errInvalidCursor
+	}
+	return &pc, nil
+}
+
+// Pagination holds the page/limit or cursor parameters shared by list endpoints.
+// Cursor pagination is keyset-based and stays stable when new rows are inserted;
+// page/limit offsets are still accepted for backward compatibility.
+type Pagination struct {
+	Page        int
+	Limit       int
+	OldestFirst bool // Order by creation time ascending instead of newest first
+	cursor      *pageCursor
+}
+
+// parsePagination reads page, limit and cursor from the query string
+func parsePagination(c *gin.Context, defaultLimit int) (*Pagination, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = defaultLimit
+	}
+
+	p := &Pagination{Page: page, Limit: limit}
+	if raw := c.Query("cursor"); raw != "" {
+		pc, err := decodeCursor(raw)
+		if err != nil {
+			return nil, err
+		}
+		p.cursor = pc
+	}
+
+	return p, nil
+}
+
+// Apply orders the query newest first (after the optional rank expression),
+// restricts it to the requested page and fetches one extra row to detect more.
+// table qualifies created_at and id so the query may join other tables.
+func (p *Pagination) Apply(query *gorm.DB, table, rankExpr string) *gorm.DB {
+	createdAt := table + ".created_at"
+	id := table + ".id"
+	backward := p.cursor != nil && p.cursor.Backward
+
+	// Backward pages scan the list in reverse and are flipped back by paginate
+	desc := p.OldestFirst == backward
+
+	if p.cursor != nil {
+		op := " > "
+		if desc {
+			op = " < "
+		}
+		after := "(" + createdAt + op + "? OR (" + createdAt + " = ? AND " + id + op + "?))"
+		t, i := p.cursor.CreatedAt, p.cursor.ID
+
+		if rankExpr == "" {
+			query = query.Where(after, t, t, i)
+		} else if backward {
+			query = query.Where("("+rankExpr+") < ? OR (("+rankExpr+") = ? AND "+after+")", p.cursor.Rank, p.cursor.Rank, t, t, i)
+		} else {
+			query = query.Where("("+rankExpr+") > ? OR (("+rankExpr+") = ? AND "+after+")", p.cursor.Rank, p.cursor.Rank, t, t, i)
+		}
+	} else {
+		query = query.Offset((p.Page - 1) * p.Limit)
+	}
+
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	rankDir := " ASC"
+	if backward {
+		rankDir = " DESC"
+	}
+	if rankExpr != "" {
+		query = query.Order(rankExpr + rankDir)
+	}
+
+	return query.Order(createdAt + dir).Order(id + dir).Limit(p.Limit + 1)
+}
+
+// paginate trims the extra row fetched by Apply, restores newest-first order for
+// backward pages and builds the pagination block with next/prev cursors.
+// key returns the cursor position of an item.
+func paginate[T any](p *Pagination, items []T, total int64, key func(*T) pageCursor) ([]T, gin.H) {
+	backward := p.cursor != nil && p.cursor.Backward
+
+	hasMore := len(items) > p.Limit
+	if hasMore {
+		items = items[:p.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	var nextCursor, prevCursor interface{}
+	if len(items) > 0 {
+		first, last := key(&items[0]), key(&items[len(items)-1])
+		first.Backward = true
+
+		// Moving backward always leaves the page we came from ahead of us
+		if hasMore || backward {
+			nextCursor = last.encode()
+		}
+		if (hasMore && backward) || (!backward && (p.cursor != nil || p.Page > 1)) {
+			prevCursor = first.encode()
+		}
+	}
+
+	return items, gin.H{
+		"page":        p.Page,
+		"limit":       p.Limit,
+		"total":       total,
+		"pages":       (total + int64(p.Limit) - 1) / int64(p.Limit),
+		"next_cursor": nextCursor,
+		"prev_cursor": prevCursor,
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// postSummary is the part of a post shown above a comment permalink
+type postSummary struct {
+	ID                uint        `json:"id"`
+	Title             string      `json:"title"`
+	User              models.User `json:"user"`
+	GameID            *uint       `json:"game_id"`
+	CommentCount      int         `json:"comment_count"`
+	IsLocked          bool        `json:"is_locked"`
+	AcceptedCommentID *uint       `json:"accepted_comment_id"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// GetCommentContext returns a comment permalink: a summary of its post, its ancestors from
+// the root comment down, and the comment itself with ?depth= levels of replies
+func (h *CommentHandler) GetCommentContext(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.Select("id", "post_id").First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+
+	opts, err := parseTreeOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var post models.Post
+	if err := h.db.Preload("User").First(&post, comment.PostID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	ancestors, err := loadAncestors(h.db, comment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comment"})
+		return
+	}
+
+	thread, err := loadCommentTrees(h.db, comment.PostID, []uint{comment.ID}, opts)
+	if err != nil || len(thread) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comment"})
+		return
+	}
+
+	decorateComments(h.db, c, post.ID, ancestors)
+	decorateComments(h.db, c, post.ID, thread)
+
+	c.JSON(http.StatusOK, gin.H{
+		"post": postSummary{
+			ID:                post.ID,
+			Title:             post.Title,
+			User:              post.User,
+			GameID:            post.GameID,
+			CommentCount:      post.CommentCount,
+			IsLocked:          post.IsLocked,
+			AcceptedCommentID: post.AcceptedCommentID,
+			CreatedAt:         post.CreatedAt,
+		},
+		"ancestors": ancestors,
+		"comment":   thread[0],
+	})
+}
+
+// loadAncestors returns the chain of comments above a comment, starting at the root
+func loadAncestors(db *gorm.DB, commentID uint) ([]models.Comment, error) {
+	chain := "WITH RECURSIVE chain(id, parent_id, depth) AS (" +
+		"SELECT id, parent_id, 0 FROM comments WHERE id = ? " +
+		"UNION ALL SELECT comments.id, comments.parent_id, chain.depth + 1 FROM comments JOIN chain ON comments.id = chain.parent_id" +
+		") SELECT id, depth FROM chain WHERE depth > 0"
+
+	ancestors := []models.Comment{}
+	err := db.Model(&models.Comment{}).
+		Select("comments.*").
+		Joins("JOIN ("+chain+") AS chain ON chain.id = comments.id", commentID).
+		Order("chain.depth DESC").
+		Preload("User").
+		Find(&ancestors).Error
+	return ancestors, err
+}
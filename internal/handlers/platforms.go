@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"forumapp/internal/gamesync"
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAllPlatforms returns all platforms
+func (h *GameHandler) GetAllPlatforms(c *gin.Context) {
+	var platforms []models.Platform
+	if err := h.db.Order("name ASC").Find(&platforms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch platforms"})
+		return
+	}
+	c.JSON(http.StatusOK, platforms)
+}
+
+// SetGamePlatforms replaces the platforms of a local game (moderators only). Imported
+// games take their platforms from RAWG.
+func (h *GameHandler) SetGamePlatforms(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can edit game platforms"})
+		return
+	}
+
+	var game models.Game
+	if err := h.db.First(&game, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+	if !game.IsLocal {
+		c.JSON(http.StatusConflict, gin.H{"error": "platforms of imported games come from RAWG"})
+		return
+	}
+
+	var req models.SetPlatformsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "platforms is required"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		platforms, err := findOrCreatePlatforms(tx, *req.Platforms)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&game).Association("Platforms").Replace(platforms)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update platforms"})
+		return
+	}
+
+	h.db.Preload("Tags").Preload("Platforms").First(&game, game.ID)
+	c.JSON(http.StatusOK, game)
+}
+
+// findOrCreatePlatforms returns the platforms named in a comma-separated list, matching
+// existing ones by name or slug and creating the rest
+func findOrCreatePlatforms(tx *gorm.DB, names string) ([]models.Platform, error) {
+	platforms := []models.Platform{}
+	seen := make(map[uint]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		slug := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+
+		platform, err := gamesync.FindOrCreatePlatform(tx, name, slug)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[platform.ID] {
+			seen[platform.ID] = true
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// platformFilter reads the ?platform= filter: one or more comma-separated platform slugs
+func platformFilter(c *gin.Context) []string {
+	var slugs []string
+	for _, slug := range strings.Split(c.Query("platform"), ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// filterGamePlatforms keeps games available on any of the ?platform= platforms
+func filterGamePlatforms(c *gin.Context, query *gorm.DB) *gorm.DB {
+	slugs := platformFilter(c)
+	if len(slugs) == 0 {
+		return query
+	}
+	return query.Where("games.id IN (SELECT game_platforms.game_id FROM game_platforms "+
+		"JOIN platforms ON platforms.id = game_platforms.platform_id WHERE platforms.slug IN ?)", slugs)
+}
+
+// filterPostPlatforms keeps posts linked to a game available on any of the ?platform= platforms
+func filterPostPlatforms(c *gin.Context, query *gorm.DB) *gorm.DB {
+	slugs := platformFilter(c)
+	if len(slugs) == 0 {
+		return query
+	}
+	return query.Where("posts.id IN (SELECT post_games.post_id FROM post_games "+
+		"JOIN game_platforms ON game_platforms.game_id = post_games.game_id "+
+		"JOIN platforms ON platforms.id = game_platforms.platform_id WHERE platforms.slug IN ?)", slugs)
+}
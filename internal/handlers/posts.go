@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -10,7 +14,11 @@ import (
 	"strings"
 	"time"
 
+	"forumapp/internal/config"
+	"forumapp/internal/events"
 	"forumapp/internal/models"
+	"forumapp/internal/preview"
+	"forumapp/internal/views"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -18,59 +26,60 @@ import (
 
 // PostHandler handles post-related requests
 type PostHandler struct {
-	db *gorm.DB
+	db      *gorm.DB
+	cfg     *config.Config
+	fetcher *preview.Fetcher
+	views   *views.Recorder
+	events  *events.Bus
 }
 
 // NewPostHandler creates a new PostHandler
-func NewPostHandler(db *gorm.DB) *PostHandler {
-	return &PostHandler{db: db}
+func NewPostHandler(db *gorm.DB, cfg *config.Config, fetcher *preview.Fetcher, recorder *views.Recorder, bus *events.Bus) *PostHandler {
+	return &PostHandler{db: db, cfg: cfg, fetcher: fetcher, views: recorder, events: bus}
 }
 
-// GetPosts returns paginated posts
-func (h *PostHandler) GetPosts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-
-	var posts []models.Post
-	var total int64
+// withPostDetails preloads the associations included in post responses
+func withPostDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Game").Preload("Game.Tags").Preload("Games").Preload("LinkPreview")
+}
 
-	query := h.db.Model(&models.Post{}).Preload("User").Preload("Game").Preload("Game.Tags")
+// postRank orders pinned posts ahead of the rest of a listing.
+// expr is the SQL form used for ordering and of computes the same rank for cursors.
+type postRank struct {
+	expr string
+	of   func(*models.Post) int
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
-		return
+var (
+	// globalPinRank puts globally pinned posts first
+	globalPinRank = postRank{
+		expr: "CASE WHEN posts.is_pinned AND posts.pin_scope = 'global' THEN 0 ELSE 1 END",
+		of: func(p *models.Post) int {
+			if p.IsPinned && p.PinScope == "global" {
+				return 0
+			}
+			return 1
+		},
 	}
 
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
-		return
+	// anyPinRank puts posts pinned globally or within their game first
+	anyPinRank = postRank{
+		expr: "CASE WHEN posts.is_pinned THEN 0 ELSE 1 END",
+		of: func(p *models.Post) int {
+			if p.IsPinned {
+				return 0
+			}
+			return 1
+		},
 	}
+)
 
-	// Get comment counts for each post
-	for i := range posts {
-		var count int64
-		h.db.Model(&models.Comment{}).Where("post_id = ?", posts[i].ID).Count(&count)
-		posts[i].CommentCount = int(count)
-	}
+// GetPosts returns paginated posts
+func (h *PostHandler) GetPosts(c *gin.Context) {
+	query := withPostDetails(h.db.Model(&models.Post{}))
 
-	c.JSON(http.StatusOK, gin.H{
-		"posts": posts,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	})
+	// Globally pinned posts always come first
+	h.listPosts(c, query, globalPinRank, "failed to fetch posts")
 }
 
 // GetPost returns a single post by ID
@@ -78,33 +87,67 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	postID := c.Param("id")
 
 	var post models.Post
-	if err := h.db.Preload("User").Preload("Game").Preload("Game.Tags").First(&post, postID).Error; err != nil {
+	if err := withPostDetails(h.db).First(&post, postID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
 		return
 	}
 
-	// Get comment count
-	var count int64
-	h.db.Model(&models.Comment{}).Where("post_id = ?", post.ID).Count(&count)
-	post.CommentCount = int(count)
+	if h.views != nil {
+		h.views.Record(post.ID, viewerKey(c))
+	}
+
+	markThreadVisited(h.db, c, &post)
+
+	posts := []models.Post{post}
+	markSavedPosts(h.db, c, posts)
+	attachPostReactions(h.db, c, posts)
+	attachPostMentions(h.db, posts)
+	attachAcceptedAnswers(h.db, posts)
 
-	c.JSON(http.StatusOK, post)
+	c.JSON(http.StatusOK, posts[0])
 }
 
 // CreatePost creates a new post
 func (h *PostHandler) CreatePost(c *gin.Context) {
 	userID := c.GetUint("user_id")
-
 	title := c.PostForm("title")
 	content := c.PostForm("content")
 	gameIDStr := c.PostForm("game_id")
 	gameName := c.PostForm("game_name") // For backward compatibility
+	postType := c.DefaultPostForm("post_type", "text")
+	linkURL := c.PostForm("link_url")
+
+	if postType != "text" && postType != "link" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "post_type must be 'text' or 'link'"})
+		return
+	}
 
-	if title == "" || content == "" {
+	// Link posts may consist of just the link
+	if title == "" || (content == "" && postType != "link") {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "title and content are required"})
 		return
 	}
 
+	if postType == "link" {
+		normalized, err := preview.ValidateURL(linkURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "link_url: " + err.Error()})
+			return
+		}
+		linkURL = normalized
+	}
+
+	// Additional games the post is cross-posted to
+	linkedIDs, err := parseGameIDs(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if gameIDStr == "" && gameName == "" && len(linkedIDs) > 0 {
+		// The first linked game becomes the primary one
+		gameIDStr = strconv.FormatUint(uint64(linkedIDs[0]), 10)
+	}
+
 	var gameID uint
 	var game models.Game
 
@@ -144,6 +187,23 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	linkedGames, err := h.loadLinkedGames(game, linkedIDs)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+
+	// Look for recent near-identical threads in the same game before committing
+	duplicates, err := h.findDuplicates(gameID, title, content, linkURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for duplicates"})
+		return
+	}
+	if h.cfg.DuplicatePostMode == "block" && hasExactDuplicate(duplicates) {
+		c.JSON(http.StatusConflict, gin.H{"error": "an identical post already exists", "duplicates": duplicates})
+		return
+	}
+
 	// Handle file upload
 	var mediaURL, mediaType string
 	file, header, err := c.Request.FormFile("file")
@@ -182,16 +242,47 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		MediaURL:  mediaURL,
 		MediaType: mediaType,
 		GameTag:   gameName, // Keep for backward compatibility
+		Games:     linkedGames,
+		PostType:  postType,
+		LinkURL:   linkURL,
 	}
 
-	if err := h.db.Create(&post).Error; err != nil {
+	if postType == "link" {
+		linkPreview, err := h.fetchLinkPreview(c, linkURL)
+		if errors.Is(err, preview.ErrBlockedAddress) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "link_url: " + err.Error()})
+			return
+		}
+		// Other fetch failures still allow the post, just without a preview
+		post.LinkPreview = linkPreview
+	}
+
+	var mentioned []models.Mention
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Only the links to the games are written, not the games themselves
+		if err := tx.Omit("Games.*").Create(&post).Error; err != nil {
+			return err
+		}
+		// Authors follow their own threads
+		if err := subscribe(tx, userID, post.ID); err != nil {
+			return err
+		}
+		mentioned, err = syncMentions(tx, userID, "post", post.ID, post.ID, post.Content)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create post"})
 		return
 	}
+	publishMentions(h.events, mentioned)
 
 	// Load user and game for response
-	h.db.Preload("User").Preload("Game").Preload("Game.Tags").First(&post, post.ID)
-	c.JSON(http.StatusCreated, post)
+	withPostDetails(h.db).First(&post, post.ID)
+	post.PossibleDuplicates = duplicates
+	post.IsSubscribed = true
+	posts := []models.Post{post}
+	attachPostMentions(h.db, posts)
+	c.JSON(http.StatusCreated, posts[0])
 }
 
 // UpdatePost updates a post (only by the author)
@@ -221,6 +312,46 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		post.Content = content
 	}
 
+	// Optionally change the primary game and/or the set of linked games
+	var linkedGames []models.Game
+	newLinkedIDs, linksGiven := c.GetPostFormArray("game_ids")
+	if primaryStr := c.PostForm("game_id"); primaryStr != "" || linksGiven {
+		if primaryStr != "" {
+			id, err := strconv.ParseUint(primaryStr, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game_id"})
+				return
+			}
+			primaryID := uint(id)
+			post.GameID = &primaryID
+		}
+		if post.GameID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "game_id is required"})
+			return
+		}
+
+		var primary models.Game
+		if err := h.db.First(&primary, *post.GameID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+			return
+		}
+
+		linkedIDs, err := parseGameIDs(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(newLinkedIDs) == 0 {
+			// Keep the current links when only the primary game changes
+			h.db.Table("post_games").Where("post_id = ?", post.ID).Pluck("game_id", &linkedIDs)
+		}
+
+		if linkedGames, err = h.loadLinkedGames(primary, linkedIDs); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+			return
+		}
+	}
+
 	// Handle new file upload
 	file, header, err := c.Request.FormFile("file")
 	if err == nil {
@@ -258,13 +389,32 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		post.MediaType = mediaType
 	}
 
-	if err := h.db.Save(&post).Error; err != nil {
+	// Only write editable fields so concurrent counter updates are not overwritten
+	var mentioned []models.Mention
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&post).Select("Title", "Content", "MediaURL", "MediaType", "GameID").Updates(&post).Error; err != nil {
+			return err
+		}
+		if linkedGames != nil {
+			if err := tx.Model(&post).Omit("Games.*").Association("Games").Replace(linkedGames); err != nil {
+				return err
+			}
+		}
+		if content != "" {
+			mentioned, err = syncMentions(tx, userID, "post", post.ID, post.ID, post.Content)
+		}
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
 		return
 	}
+	publishMentions(h.events, mentioned)
 
-	h.db.Preload("User").Preload("Game").Preload("Game.Tags").First(&post, post.ID)
-	c.JSON(http.StatusOK, post)
+	withPostDetails(h.db).First(&post, post.ID)
+	posts := []models.Post{post}
+	attachPostMentions(h.db, posts)
+	c.JSON(http.StatusOK, posts[0])
 }
 
 // DeletePost deletes a post (by author, moderator, or owner)
@@ -285,17 +435,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	// Delete associated media file
-	if post.MediaURL != "" {
-		oldPath := "." + post.MediaURL
-		os.Remove(oldPath)
-	}
-
-	// Delete all comments for this post
-	h.db.Where("post_id = ?", post.ID).Delete(&models.Comment{})
-
-	// Delete the post
-	if err := h.db.Delete(&post).Error; err != nil {
+	if err := deletePost(h.db, &post); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete post"})
 		return
 	}
@@ -303,171 +443,246 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "post deleted"})
 }
 
-// SearchPosts searches posts by query and/or game
-func (h *PostHandler) SearchPosts(c *gin.Context) {
-	query := c.Query("q")
-	gameID := c.Query("game_id")
-	gameTag := c.Query("game_tag") // Backward compatibility
-	tagSlug := c.Query("tag")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+// deletePost removes a post with everything attached to it in one transaction, then its
+// media file once the transaction has committed
+func deletePost(db *gorm.DB, post *models.Post) error {
+	if err := db.Transaction(func(tx *gorm.DB) error { return deletePostRows(tx, post) }); err != nil {
+		return err
+	}
 
-	if page < 1 {
-		page = 1
+	removePostMedia(post)
+	return nil
+}
+
+// deletePostRows deletes a post within tx. Comments and their votes, the link preview,
+// views and subscriptions go by ON DELETE CASCADE; rows that reference the post or its
+// comments by target type are deleted here.
+func deletePostRows(tx *gorm.DB, post *models.Post) error {
+	// Drop mentions in the post and its comments
+	if err := tx.Where("post_id = ?", post.ID).Delete(&models.Mention{}).Error; err != nil {
+		return err
+	}
+
+	// Drop reactions to and bookmarks of the post and its comments
+	commentIDs := tx.Model(&models.Comment{}).Select("id").Where("post_id = ?", post.ID)
+	for _, model := range []interface{}{&models.Reaction{}, &models.SavedItem{}} {
+		if err := tx.Where("(target_type = ? AND target_id = ?) OR (target_type = ? AND target_id IN (?))",
+			"post", post.ID, "comment", commentIDs).Delete(model).Error; err != nil {
+			return err
+		}
 	}
-	if limit < 1 || limit > 50 {
-		limit = 10
+
+	// Unlink the post from its games
+	if err := tx.Model(post).Association("Games").Clear(); err != nil {
+		return err
 	}
 
-	offset := (page - 1) * limit
+	return tx.Delete(post).Error
+}
 
-	var posts []models.Post
-	var total int64
+// removePostMedia deletes a post's uploaded media file, if it has one
+func removePostMedia(post *models.Post) {
+	if post.MediaURL != "" {
+		os.Remove("." + post.MediaURL)
+	}
+}
 
-	dbQuery := h.db.Model(&models.Post{}).Preload("User").Preload("Game").Preload("Game.Tags")
+// SearchPosts searches posts by query and/or game, optionally only ?answered=true or false threads
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	query := c.Query("q")
+	gameID := c.Query("game_id")
+	gameTag := c.Query("game_tag") // Backward compatibility
+	tagSlug := c.Query("tag")
+
+	dbQuery := withPostDetails(h.db.Model(&models.Post{}))
 
 	// Apply filters
 	if query != "" {
-		dbQuery = dbQuery.Where("title LIKE ? OR content LIKE ?", "%"+query+"%", "%"+query+"%")
+		dbQuery = dbQuery.Where("posts.title LIKE ? OR posts.content LIKE ?", "%"+query+"%", "%"+query+"%")
 	}
+	// Game filters match a post through any of its linked games
 	if gameID != "" {
-		dbQuery = dbQuery.Where("game_id = ?", gameID)
+		dbQuery = dbQuery.Where("posts.id IN (SELECT post_id FROM post_games WHERE game_id = ?)", gameID)
 	}
 	if gameTag != "" {
 		// Backward compatibility: search by game title
-		dbQuery = dbQuery.Joins("JOIN games ON games.id = posts.game_id").
-			Where("games.title = ?", gameTag)
+		dbQuery = dbQuery.Where("posts.id IN (SELECT post_games.post_id FROM post_games "+
+			"JOIN games ON games.id = post_games.game_id WHERE games.title = ?)", gameTag)
 	}
 	if tagSlug != "" {
 		// Filter by tag
-		dbQuery = dbQuery.Joins("JOIN games ON games.id = posts.game_id").
-			Joins("JOIN game_tags ON game_tags.game_id = games.id").
-			Joins("JOIN tags ON tags.id = game_tags.tag_id").
-			Where("tags.slug = ?", tagSlug)
+		dbQuery = dbQuery.Where("posts.id IN (SELECT post_games.post_id FROM post_games "+
+			"JOIN game_tags ON game_tags.game_id = post_games.game_id "+
+			"JOIN tags ON tags.id = game_tags.tag_id WHERE tags.slug = ?)", tagSlug)
 	}
-
-	if err := dbQuery.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+	dbQuery, err := filterAnswered(c, dbQuery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := dbQuery.Order("created_at DESC").Limit(limit).Offset(offset).Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search posts"})
+	h.listPosts(c, dbQuery, postRank{}, "failed to search posts")
+}
+
+// GetPostsByGame returns posts linked to a specific game, optionally filtered by ?answered=
+func (h *PostHandler) GetPostsByGame(c *gin.Context) {
+	gameID := c.Param("game_id")
+
+	query := withPostDetails(h.db.Model(&models.Post{})).
+		Where("posts.id IN (SELECT post_id FROM post_games WHERE game_id = ?)", gameID)
+	query, err := filterAnswered(c, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get comment counts for each post
-	for i := range posts {
-		var count int64
-		h.db.Model(&models.Comment{}).Where("post_id = ?", posts[i].ID).Count(&count)
-		posts[i].CommentCount = int(count)
-	}
+	// Posts pinned globally or within this game come first
+	h.listPosts(c, query, anyPinRank, "failed to fetch posts")
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"posts": posts,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	})
+// GetUserPosts returns posts by a specific user
+func (h *PostHandler) GetUserPosts(c *gin.Context) {
+	userID := c.Param("user_id")
+
+	query := withPostDetails(h.db.Model(&models.Post{})).Where("user_id = ?", userID)
+
+	h.listPosts(c, query, postRank{}, "failed to fetch posts")
 }
 
-// GetPostsByGame returns posts for a specific game
-func (h *PostHandler) GetPostsByGame(c *gin.Context) {
-	gameID := c.Param("game_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+// listPosts paginates a post query and writes the standard listing response. Every
+// listing can be narrowed to posts about games on ?platform=.
+func (h *PostHandler) listPosts(c *gin.Context, query *gorm.DB, rank postRank, fetchError string) {
+	query = filterPostPlatforms(c, query)
 
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 10
+	pagination, err := parsePagination(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	offset := (page - 1) * limit
-
 	var posts []models.Post
 	var total int64
 
-	query := h.db.Model(&models.Post{}).Where("game_id = ?", gameID).
-		Preload("User").Preload("Game").Preload("Game.Tags")
-
 	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
 		return
 	}
 
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
+	if err := pagination.Apply(query, "posts", rank.expr).Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fetchError})
 		return
 	}
 
-	// Get comment counts for each post
-	for i := range posts {
-		var count int64
-		h.db.Model(&models.Comment{}).Where("post_id = ?", posts[i].ID).Count(&count)
-		posts[i].CommentCount = int(count)
-	}
+	posts, page := paginate(pagination, posts, total, func(p *models.Post) pageCursor {
+		pc := pageCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+		if rank.of != nil {
+			pc.Rank = float64(rank.of(p))
+		}
+		return pc
+	})
+
+	decoratePosts(h.db, c, posts)
 
 	c.JSON(http.StatusOK, gin.H{
-		"posts": posts,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
+		"posts":      posts,
+		"pagination": page,
 	})
 }
 
-// GetUserPosts returns posts by a specific user
-func (h *PostHandler) GetUserPosts(c *gin.Context) {
-	userID := c.Param("user_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+// decoratePosts fills in the per-viewer and derived fields of listed posts
+func decoratePosts(db *gorm.DB, c *gin.Context, posts []models.Post) {
+	markSavedPosts(db, c, posts)
+	markSubscribedPosts(db, c, posts)
+	attachPostReactions(db, c, posts)
+	attachPostMentions(db, posts)
+	attachAcceptedAnswers(db, posts)
+}
+
+// viewerKey identifies a viewer for view deduplication: the user ID when
+// authenticated, otherwise a hashed fingerprint of the client IP and user agent
+func viewerKey(c *gin.Context) string {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+	sum := sha256.Sum256([]byte(c.ClientIP() + "|" + c.Request.UserAgent()))
+	return "a:" + hex.EncodeToString(sum[:12])
+}
 
-	if page < 1 {
-		page = 1
+// fetchLinkPreview fetches the metadata for a link post, bounded by the request context
+func (h *PostHandler) fetchLinkPreview(c *gin.Context, link string) (*models.LinkPreview, error) {
+	if h.fetcher == nil {
+		return nil, nil
 	}
-	if limit < 1 || limit > 50 {
-		limit = 10
+
+	p, err := h.fetcher.Fetch(c.Request.Context(), link)
+	if err != nil {
+		log.Printf("link preview for %s failed: %v", link, err)
+		return nil, err
 	}
 
-	offset := (page - 1) * limit
+	return &models.LinkPreview{
+		URL:         p.URL,
+		Title:       p.Title,
+		Description: p.Description,
+		ImageURL:    p.ImageURL,
+		SiteName:    p.SiteName,
+		Provider:    p.Provider,
+		EmbedHTML:   p.EmbedHTML,
+		FetchedAt:   time.Now(),
+	}, nil
+}
 
-	var posts []models.Post
-	var total int64
+// maxLinkedGames caps how many games a single post can be cross-posted to
+const maxLinkedGames = 5
+
+// parseGameIDs reads the linked game IDs from repeated or comma-separated game_ids fields
+func parseGameIDs(c *gin.Context) ([]uint, error) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, value := range c.PostFormArray("game_ids") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid game_ids")
+			}
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				ids = append(ids, uint(id))
+			}
+		}
+	}
 
-	query := h.db.Model(&models.Post{}).Where("user_id = ?", userID).
-		Preload("User").Preload("Game").Preload("Game.Tags")
+	if len(ids) > maxLinkedGames {
+		return nil, fmt.Errorf("a post can be linked to at most %d games", maxLinkedGames)
+	}
+	return ids, nil
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
-		return
+// loadLinkedGames returns the primary game followed by the other linked games
+func (h *PostHandler) loadLinkedGames(primary models.Game, ids []uint) ([]models.Game, error) {
+	var others []uint
+	for _, id := range ids {
+		if id != primary.ID {
+			others = append(others, id)
+		}
 	}
 
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
-		return
+	games := []models.Game{primary}
+	if len(others) == 0 {
+		return games, nil
 	}
 
-	// Get comment counts for each post
-	for i := range posts {
-		var count int64
-		h.db.Model(&models.Comment{}).Where("post_id = ?", posts[i].ID).Count(&count)
-		posts[i].CommentCount = int(count)
+	var found []models.Game
+	if err := h.db.Where("id IN ?", others).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	if len(found) != len(others) {
+		return nil, gorm.ErrRecordNotFound
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"posts": posts,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	})
+	return append(games, found...), nil
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"forumapp/internal/rawg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RAWGCacheHandler exposes the RAWG response cache to moderators
+type RAWGCacheHandler struct {
+	cache *rawg.CachedClient
+}
+
+// NewRAWGCacheHandler creates a new RAWG cache handler
+func NewRAWGCacheHandler(cache *rawg.CachedClient) *RAWGCacheHandler {
+	return &RAWGCacheHandler{cache: cache}
+}
+
+// GetStats returns cache hit/miss counters and the number of stored entries
+func (h *RAWGCacheHandler) GetStats(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can view the RAWG cache"})
+		return
+	}
+
+	stats, err := h.cache.Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch cache stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// Purge deletes cached RAWG responses. ?kind=search|game limits it to one kind and
+// ?expired=true keeps entries that can still be served.
+func (h *RAWGCacheHandler) Purge(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can purge the RAWG cache"})
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != "search" && kind != "game" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be 'search' or 'game'"})
+		return
+	}
+
+	purged, err := h.cache.Purge(kind, c.Query("expired") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge cache"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
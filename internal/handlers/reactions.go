@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"forumapp/internal/config"
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxReactionImageSize caps uploaded custom reaction images
+const maxReactionImageSize = 256 << 10
+
+// shortcodePattern is the allowed form of a custom reaction shortcode
+var shortcodePattern = regexp.MustCompile(`^[a-z0-9_]{2,32}$`)
+
+// ReactionHandler handles emoji reactions and custom per-game reactions
+type ReactionHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewReactionHandler creates a new ReactionHandler
+func NewReactionHandler(db *gorm.DB, cfg *config.Config) *ReactionHandler {
+	return &ReactionHandler{db: db, cfg: cfg}
+}
+
+// GetReactionSet returns the standard reactions and, for a game, its custom ones
+func (h *ReactionHandler) GetReactionSet(c *gin.Context) {
+	custom := []models.CustomReaction{}
+	if gameID := c.Query("game_id"); gameID != "" {
+		if err := h.db.Where("game_id = ?", gameID).Order("shortcode ASC").Find(&custom).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reactions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"standard": h.cfg.Reactions,
+		"custom":   custom,
+	})
+}
+
+// ReactToPost toggles the authenticated user's reaction on a post
+func (h *ReactionHandler) ReactToPost(c *gin.Context) {
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.toggle(c, "post", post.ID, post.ID)
+}
+
+// ReactToComment toggles the authenticated user's reaction on a comment
+func (h *ReactionHandler) ReactToComment(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+
+	h.toggle(c, "comment", comment.ID, comment.PostID)
+}
+
+// CreateCustomReaction uploads an image reaction for a game (moderators only)
+func (h *ReactionHandler) CreateCustomReaction(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can add custom reactions"})
+		return
+	}
+
+	var game models.Game
+	if err := h.db.First(&game, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+
+	shortcode := strings.ToLower(strings.Trim(c.PostForm("shortcode"), ": "))
+	if !shortcodePattern.MatchString(shortcode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "shortcode must be 2-32 lowercase letters, digits or underscores"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "an image file is required"})
+		return
+	}
+	defer file.Close()
+
+	if !strings.HasPrefix(header.Header.Get("Content-Type"), "image/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported file type"})
+		return
+	}
+	if header.Size > maxReactionImageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reaction images must be at most 256KB"})
+		return
+	}
+
+	var existing int64
+	h.db.Model(&models.CustomReaction{}).Where("game_id = ? AND shortcode = ?", game.ID, shortcode).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "this game already has a reaction with that shortcode"})
+		return
+	}
+
+	filename := fmt.Sprintf("reaction_%d_%s%s", game.ID, shortcode, strings.ToLower(filepath.Ext(header.Filename)))
+	if err := c.SaveUploadedFile(header, filepath.Join(h.cfg.UploadDir, filename)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+		return
+	}
+
+	reaction := models.CustomReaction{
+		GameID:      game.ID,
+		Shortcode:   shortcode,
+		ImageURL:    "/uploads/" + filename,
+		CreatedByID: c.GetUint("user_id"),
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reaction).Error; err != nil {
+			return err
+		}
+		return recordModerationAction(tx, reaction.CreatedByID, "add_reaction", "game", game.ID, ":"+shortcode+":")
+	})
+	if err != nil {
+		os.Remove(filepath.Join(h.cfg.UploadDir, filename))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reaction)
+}
+
+// DeleteCustomReaction removes a game's custom reaction and its uses in that game (moderators only)
+func (h *ReactionHandler) DeleteCustomReaction(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can remove custom reactions"})
+		return
+	}
+
+	var reaction models.CustomReaction
+	if err := h.db.Where("id = ? AND game_id = ?", c.Param("reaction_id"), c.Param("id")).First(&reaction).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reaction not found"})
+		return
+	}
+
+	emoji := ":" + reaction.Shortcode + ":"
+	gamePosts := h.db.Table("post_games").Select("post_id").Where("game_id = ?", reaction.GameID)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("emoji = ? AND target_type = ? AND target_id IN (?)", emoji, "post", gamePosts).
+			Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("emoji = ? AND target_type = ? AND target_id IN (?)", emoji, "comment",
+			tx.Model(&models.Comment{}).Select("id").Where("post_id IN (?)", gamePosts)).
+			Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&reaction).Error; err != nil {
+			return err
+		}
+		return recordModerationAction(tx, c.GetUint("user_id"), "remove_reaction", "game", reaction.GameID, emoji)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove reaction"})
+		return
+	}
+
+	os.Remove(filepath.Join(h.cfg.UploadDir, filepath.Base(reaction.ImageURL)))
+	c.JSON(http.StatusOK, gin.H{"message": "reaction removed"})
+}
+
+// toggle adds the reaction if the user has not made it yet and removes it otherwise
+func (h *ReactionHandler) toggle(c *gin.Context, targetType string, targetID, postID uint) {
+	userID := c.GetUint("user_id")
+	var req models.ReactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.isAllowed(req.Emoji, postID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown reaction"})
+		return
+	}
+
+	reacted := false
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND target_type = ? AND target_id = ? AND emoji = ?", userID, targetType, targetID, req.Emoji).
+			Delete(&models.Reaction{})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		// A concurrent toggle may have added it already; either way it is now set
+		reacted = true
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Reaction{
+			UserID:     userID,
+			TargetType: targetType,
+			TargetID:   targetID,
+			Emoji:      req.Emoji,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"emoji":     req.Emoji,
+		"reacted":   reacted,
+		"reactions": reactionCounts(h.db, userID, targetType, []uint{targetID})[targetID],
+	})
+}
+
+// isAllowed reports whether an emoji is in the standard set or a custom reaction of one of the post's games
+func (h *ReactionHandler) isAllowed(emoji string, postID uint) bool {
+	for _, standard := range h.cfg.Reactions {
+		if emoji == standard {
+			return true
+		}
+	}
+
+	if len(emoji) < 3 || !strings.HasPrefix(emoji, ":") || !strings.HasSuffix(emoji, ":") {
+		return false
+	}
+
+	var count int64
+	h.db.Model(&models.CustomReaction{}).
+		Where("shortcode = ? AND game_id IN (SELECT game_id FROM post_games WHERE post_id = ?)", strings.Trim(emoji, ":"), postID).
+		Count(&count)
+	return count > 0
+}
+
+// reactionCounts aggregates the reactions of several targets in one query, most used first,
+// flagging the ones made by the viewer
+func reactionCounts(db *gorm.DB, viewerID uint, targetType string, ids []uint) map[uint][]models.ReactionCount {
+	counts := make(map[uint][]models.ReactionCount)
+	if len(ids) == 0 {
+		return counts
+	}
+
+	var rows []struct {
+		TargetID uint
+		Emoji    string
+		Count    int
+		Reacted  bool
+	}
+	db.Model(&models.Reaction{}).
+		Select("target_id, emoji, COUNT(*) AS count, MAX(CASE WHEN user_id = ? THEN 1 ELSE 0 END) AS reacted", viewerID).
+		Where("target_type = ? AND target_id IN ?", targetType, ids).
+		Group("target_id, emoji").
+		Order("count DESC, MIN(created_at) ASC").
+		Scan(&rows)
+
+	for _, row := range rows {
+		counts[row.TargetID] = append(counts[row.TargetID], models.ReactionCount{
+			Emoji:   row.Emoji,
+			Count:   row.Count,
+			Reacted: row.Reacted,
+		})
+	}
+	return counts
+}
+
+// attachPostReactions sets the aggregated reactions on a page of posts
+func attachPostReactions(db *gorm.DB, c *gin.Context, posts []models.Post) {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	counts := reactionCounts(db, c.GetUint("user_id"), "post", ids)
+	for i := range posts {
+		posts[i].Reactions = counts[posts[i].ID]
+	}
+}
+
+// attachCommentReactions sets the aggregated reactions on comments and all their loaded replies
+func attachCommentReactions(db *gorm.DB, c *gin.Context, comments []models.Comment) {
+	all := flattenComments(comments)
+	ids := make([]uint, len(all))
+	for i, comment := range all {
+		ids[i] = comment.ID
+	}
+
+	counts := reactionCounts(db, c.GetUint("user_id"), "comment", ids)
+	for _, comment := range all {
+		comment.Reactions = counts[comment.ID]
+	}
+}
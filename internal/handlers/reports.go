@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportHandler handles user reports and the moderation queue
+type ReportHandler struct {
+	db *gorm.DB
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(db *gorm.DB) *ReportHandler {
+	return &ReportHandler{db: db}
+}
+
+// ReportGroup is the moderation queue entry for all open reports of one target
+type ReportGroup struct {
+	TargetType      string          `json:"target_type"`
+	TargetID        uint            `json:"target_id"`
+	ReportCount     int64           `json:"report_count"`
+	FirstReportedAt time.Time       `json:"first_reported_at"`
+	LastReportedAt  time.Time       `json:"last_reported_at"`
+	Categories      map[string]int  `json:"categories"`
+	Target          interface{}     `json:"target"` // The reported post, comment or user; nil if already gone
+	Reports         []models.Report `json:"reports"`
+}
+
+// CreateReport files a report against a post, comment or user
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req models.CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.loadTarget(req.TargetType, req.TargetID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": req.TargetType + " not found"})
+		return
+	}
+
+	// One open report per reporter and target
+	var existing int64
+	h.db.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?", userID, req.TargetType, req.TargetID, "open").
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "you have already reported this " + req.TargetType})
+		return
+	}
+
+	report := models.Report{
+		ReporterID: userID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Category:   req.Category,
+		Details:    req.Details,
+		Status:     "open",
+	}
+	if err := h.db.Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetMyReports returns the authenticated user's reports with their status and outcome
+func (h *ReportHandler) GetMyReports(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	pagination, err := parsePagination(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.Report{}).Where("reporter_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count reports"})
+		return
+	}
+
+	var reports []models.Report
+	if err := pagination.Apply(query, "reports", "").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reports"})
+		return
+	}
+
+	reports, page := paginate(pagination, reports, total, func(r *models.Report) pageCursor {
+		return pageCursor{CreatedAt: r.CreatedAt, ID: r.ID}
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":    reports,
+		"pagination": page,
+	})
+}
+
+// GetReportQueue returns open reports grouped per target, most reported first
+func (h *ReportHandler) GetReportQueue(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can view the report queue"})
+		return
+	}
+
+	// Groups are ranked by report count, so the queue pages by offset rather than cursor
+	pagination, err := parsePagination(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pagination.Page, pagination.Limit
+
+	// openReports builds a fresh query over the open reports in the queue
+	openReports := func() *gorm.DB {
+		query := h.db.Model(&models.Report{}).Where("status = ?", "open")
+		if targetType := c.Query("target_type"); targetType != "" {
+			query = query.Where("target_type = ?", targetType)
+		}
+		return query
+	}
+
+	var total int64
+	if err := h.db.Table("(?) AS report_groups", openReports().Select("target_type, target_id").Group("target_type, target_id")).
+		Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count reports"})
+		return
+	}
+
+	var rows []struct {
+		TargetType  string
+		TargetID    uint
+		ReportCount int64
+	}
+	if err := openReports().Select("target_type, target_id, COUNT(*) AS report_count, MAX(created_at) AS last_reported_at").
+		Group("target_type, target_id").
+		Order("report_count DESC, last_reported_at DESC").
+		Limit(limit).Offset((page - 1) * limit).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch report queue"})
+		return
+	}
+
+	// Load the open reports of every group on the page at once
+	type reportTarget struct {
+		targetType string
+		targetID   uint
+	}
+	byTarget := make(map[reportTarget][]models.Report, len(rows))
+	if len(rows) > 0 {
+		targets := make([][]interface{}, len(rows))
+		for i, row := range rows {
+			targets[i] = []interface{}{row.TargetType, row.TargetID}
+		}
+		var reports []models.Report
+		if err := h.db.Preload("Reporter").
+			Where("status = ? AND (target_type, target_id) IN ?", "open", targets).
+			Order("created_at ASC, id ASC").Find(&reports).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reports"})
+			return
+		}
+		for _, report := range reports {
+			key := reportTarget{report.TargetType, report.TargetID}
+			byTarget[key] = append(byTarget[key], report)
+		}
+	}
+
+	groups := make([]ReportGroup, 0, len(rows))
+	for _, row := range rows {
+		group := ReportGroup{
+			TargetType:  row.TargetType,
+			TargetID:    row.TargetID,
+			ReportCount: row.ReportCount,
+			Categories:  make(map[string]int),
+			Reports:     byTarget[reportTarget{row.TargetType, row.TargetID}],
+		}
+
+		for _, report := range group.Reports {
+			group.Categories[report.Category]++
+		}
+		if len(group.Reports) > 0 {
+			group.FirstReportedAt = group.Reports[0].CreatedAt
+			group.LastReportedAt = group.Reports[len(group.Reports)-1].CreatedAt
+		}
+
+		if target, err := h.loadTarget(row.TargetType, row.TargetID); err == nil {
+			group.Target = target
+		}
+
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// ResolveReports closes all open reports of a target, optionally deleting, locking or banning in the same step
+func (h *ReportHandler) ResolveReports(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can resolve reports"})
+		return
+	}
+
+	var req models.ResolveReportsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Action == "" {
+		req.Action = "none"
+	}
+
+	h.closeReports(c, "resolved", req.Action, req.Note)
+}
+
+// DismissReports closes all open reports of a target without taking action
+func (h *ReportHandler) DismissReports(c *gin.Context) {
+	if !isModerator(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only moderators can dismiss reports"})
+		return
+	}
+
+	h.closeReports(c, "dismissed", "none", bindReason(c))
+}
+
+// closeReports applies the moderation action and records the outcome on every open report of the target
+func (h *ReportHandler) closeReports(c *gin.Context, status, action, note string) {
+	moderatorID := c.GetUint("user_id")
+	targetType := c.Param("target_type")
+	targetID64, err := strconv.ParseUint(c.Param("target_id"), 10, 32)
+	if err != nil || (targetType != "post" && targetType != "comment" && targetType != "user") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report target"})
+		return
+	}
+	targetID := uint(targetID64)
+
+	var open int64
+	h.db.Model(&models.Report{}).
+		Where("status = ? AND target_type = ? AND target_id = ?", "open", targetType, targetID).
+		Count(&open)
+	if open == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no open reports for this target"})
+		return
+	}
+
+	var target interface{}
+	if action != "none" {
+		if target, err = h.loadTarget(targetType, targetID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": targetType + " no longer exists"})
+			return
+		}
+	}
+
+	// The action, its audit entry and the report updates succeed or fail together
+	outcome := "no_action"
+	if status == "dismissed" {
+		outcome = "dismissed"
+	}
+	var code int
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if target != nil {
+			var err error
+			if outcome, code, err = applyAction(tx, moderatorID, target, action, note); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Report{}).
+			Where("status = ? AND target_type = ? AND target_id = ?", "open", targetType, targetID).
+			Updates(map[string]interface{}{
+				"status":          status,
+				"outcome":         outcome,
+				"resolution_note": note,
+				"resolved_by_id":  moderatorID,
+				"resolved_at":     now,
+			}).Error; err != nil {
+			return err
+		}
+		return recordModerationAction(tx, moderatorID, status+"_reports", targetType, targetID, note)
+	})
+	if err != nil {
+		if code != 0 {
+			c.JSON(code, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reports"})
+		return
+	}
+
+	if post, ok := target.(*models.Post); ok && outcome == "deleted" {
+		removePostMedia(post)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reports " + status, "outcome": outcome, "reports": open})
+}
+
+// applyAction performs the moderation action chosen while resolving reports within tx and
+// returns the outcome recorded on the reports, or an error with its HTTP status
+func applyAction(tx *gorm.DB, moderatorID uint, target interface{}, action, note string) (string, int, error) {
+	switch action {
+	case "delete":
+		var err error
+		var targetType string
+		switch t := target.(type) {
+		case *models.Post:
+			targetType = "post"
+			if err = deletePostRows(tx, t); err == nil {
+				err = recordModerationAction(tx, moderatorID, "delete", "post", t.ID, note)
+			}
+		case *models.Comment:
+			// deleteComment audits moderator removals itself
+			targetType = "comment"
+			_, err = deleteComment(tx, t, commentRemoval{byID: moderatorID, byModerator: true, reason: note})
+		default:
+			return "", http.StatusBadRequest, errors.New("users cannot be deleted; ban them instead")
+		}
+		if err != nil {
+			return "", http.StatusInternalServerError, errors.New("failed to delete " + targetType)
+		}
+		return "deleted", 0, nil
+
+	case "lock":
+		post, ok := target.(*models.Post)
+		if !ok {
+			return "", http.StatusBadRequest, errors.New("only posts can be locked")
+		}
+		if err := tx.Model(post).Update("is_locked", true).Error; err != nil {
+			return "", http.StatusInternalServerError, errors.New("failed to lock post")
+		}
+		if err := recordModerationAction(tx, moderatorID, "lock", "post", post.ID, note); err != nil {
+			return "", http.StatusInternalServerError, errors.New("failed to lock post")
+		}
+		return "locked", 0, nil
+
+	case "ban":
+		// Reported content bans its author
+		var userID uint
+		switch t := target.(type) {
+		case *models.Post:
+			userID = t.UserID
+		case *models.Comment:
+			userID = t.UserID
+		case *models.User:
+			userID = t.ID
+		}
+		if status, err := banUser(tx, moderatorID, userID, note); err != nil {
+			return "", status, err
+		}
+		return "banned", 0, nil
+	}
+
+	return "", http.StatusBadRequest, errors.New("unknown action")
+}
+
+// banUser bans a regular user and records the action
+func banUser(db *gorm.DB, moderatorID, userID uint, reason string) (int, error) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return http.StatusNotFound, errors.New("user not found")
+	}
+	if isModerator(user.Role) {
+		return http.StatusForbidden, errors.New("moderators cannot be banned")
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"is_banned": true, "banned_at": now}).Error; err != nil {
+			return err
+		}
+		return recordModerationAction(tx, moderatorID, "ban", "user", user.ID, reason)
+	})
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("failed to ban user")
+	}
+	return 0, nil
+}
+
+// loadTarget loads the reported post, comment or user
+func (h *ReportHandler) loadTarget(targetType string, targetID uint) (interface{}, error) {
+	switch targetType {
+	case "post":
+		var post models.Post
+		if err := h.db.Preload("User").First(&post, targetID).Error; err != nil {
+			return nil, err
+		}
+		return &post, nil
+	case "comment":
+		var comment models.Comment
+		if err := h.db.Preload("User").First(&comment, targetID).Error; err != nil {
+			return nil, err
+		}
+		return &comment, nil
+	case "user":
+		var user models.User
+		if err := h.db.First(&user, targetID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
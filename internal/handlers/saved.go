@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SavedHandler handles bookmarking of posts and comments
+type SavedHandler struct {
+	db *gorm.DB
+}
+
+// NewSavedHandler creates a new SavedHandler
+func NewSavedHandler(db *gorm.DB) *SavedHandler {
+	return &SavedHandler{db: db}
+}
+
+// SavePost bookmarks a post for the authenticated user
+func (h *SavedHandler) SavePost(c *gin.Context) {
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	h.save(c, "post", post.ID)
+}
+
+// UnsavePost removes a post bookmark for the authenticated user
+func (h *SavedHandler) UnsavePost(c *gin.Context) {
+	h.unsave(c, "post")
+}
+
+// SaveComment bookmarks a comment for the authenticated user
+func (h *SavedHandler) SaveComment(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+
+	h.save(c, "comment", comment.ID)
+}
+
+// UnsaveComment removes a comment bookmark for the authenticated user
+func (h *SavedHandler) UnsaveComment(c *gin.Context) {
+	h.unsave(c, "comment")
+}
+
+// GetSavedItems returns the authenticated user's saved posts and comments
+func (h *SavedHandler) GetSavedItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	pagination, err := parsePagination(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.SavedItem{}).Where("user_id = ?", userID)
+	if targetType := c.Query("type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if folder, ok := c.GetQuery("folder"); ok {
+		query = query.Where("folder = ?", folder)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count saved items"})
+		return
+	}
+
+	var items []models.SavedItem
+	if err := pagination.Apply(query, "saved_items", "").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch saved items"})
+		return
+	}
+
+	items, page := paginate(pagination, items, total, func(item *models.SavedItem) pageCursor {
+		return pageCursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	})
+
+	if err := h.loadTargets(items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch saved items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":      items,
+		"pagination": page,
+	})
+}
+
+// GetSavedFolders returns the authenticated user's folders with item counts
+func (h *SavedHandler) GetSavedFolders(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var folders []struct {
+		Folder string `json:"folder"`
+		Count  int64  `json:"count"`
+	}
+	if err := h.db.Model(&models.SavedItem{}).
+		Select("folder, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("folder").
+		Order("folder ASC").
+		Scan(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch folders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// save creates or updates a bookmark, moving it to the requested folder
+func (h *SavedHandler) save(c *gin.Context, targetType string, targetID uint) {
+	var req models.SaveItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := models.SavedItem{
+		UserID:     c.GetUint("user_id"),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Folder:     req.Folder,
+	}
+
+	// Saving twice is idempotent and only moves the item between folders
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "target_type"}, {Name: "target_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"folder"}),
+	}).Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save " + targetType})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": targetType + " saved", "folder": item.Folder})
+}
+
+// unsave removes a bookmark if it exists
+func (h *SavedHandler) unsave(c *gin.Context, targetType string) {
+	if err := h.db.Where("user_id = ? AND target_type = ? AND target_id = ?", c.GetUint("user_id"), targetType, c.Param("id")).
+		Delete(&models.SavedItem{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsave " + targetType})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": targetType + " unsaved"})
+}
+
+// loadTargets attaches the saved posts and comments to their items in two queries
+func (h *SavedHandler) loadTargets(items []models.SavedItem) error {
+	var postIDs, commentIDs []uint
+	for _, item := range items {
+		if item.TargetType == "post" {
+			postIDs = append(postIDs, item.TargetID)
+		} else {
+			commentIDs = append(commentIDs, item.TargetID)
+		}
+	}
+
+	posts := make(map[uint]*models.Post)
+	if len(postIDs) > 0 {
+		var found []models.Post
+		if err := withPostDetails(h.db).Where("id IN ?", postIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for i := range found {
+			found[i].IsSaved = true
+			posts[found[i].ID] = &found[i]
+		}
+	}
+
+	comments := make(map[uint]*models.Comment)
+	if len(commentIDs) > 0 {
+		var found []models.Comment
+		if err := h.db.Preload("User").Where("id IN ?", commentIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for i := range found {
+			comments[found[i].ID] = &found[i]
+		}
+	}
+
+	for i := range items {
+		if items[i].TargetType == "post" {
+			items[i].Post = posts[items[i].TargetID]
+		} else {
+			items[i].Comment = comments[items[i].TargetID]
+		}
+	}
+
+	return nil
+}
+
+// markSavedPosts sets IsSaved on posts bookmarked by the current user, if any
+func markSavedPosts(db *gorm.DB, c *gin.Context, posts []models.Post) {
+	userID := c.GetUint("user_id")
+	if userID == 0 || len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	var savedIDs []uint
+	db.Model(&models.SavedItem{}).
+		Where("user_id = ? AND target_type = ? AND target_id IN ?", userID, "post", ids).
+		Pluck("target_id", &savedIDs)
+
+	saved := make(map[uint]bool, len(savedIDs))
+	for _, id := range savedIDs {
+		saved[id] = true
+	}
+	for i := range posts {
+		posts[i].IsSaved = saved[posts[i].ID]
+	}
+}
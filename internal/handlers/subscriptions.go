@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// newCommentsSince counts comments by others on a subscribed thread since the subscriber's last visit
+const newCommentsSince = "(SELECT COUNT(*) FROM comments WHERE comments.post_id = thread_subscriptions.post_id " +
+	"AND comments.created_at > thread_subscriptions.last_visited_at AND comments.user_id <> thread_subscriptions.user_id)"
+
+// SubscriptionHandler handles following post discussions
+type SubscriptionHandler struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionHandler creates a new SubscriptionHandler
+func NewSubscriptionHandler(db *gorm.DB) *SubscriptionHandler {
+	return &SubscriptionHandler{db: db}
+}
+
+// Subscribe follows a post's discussion; subscribing again keeps the last visit
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	var post models.Post
+	if err := h.db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	if err := subscribe(h.db, c.GetUint("user_id"), post.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "subscribed"})
+}
+
+// Unsubscribe stops following a post's discussion
+func (h *SubscriptionHandler) Unsubscribe(c *gin.Context) {
+	if err := h.db.Where("user_id = ? AND post_id = ?", c.GetUint("user_id"), c.Param("id")).
+		Delete(&models.ThreadSubscription{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed"})
+}
+
+// GetSubscriptions returns the authenticated user's followed threads with new comment counts
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	pagination, err := parsePagination(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.ThreadSubscription{}).Where("thread_subscriptions.user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where(newCommentsSince + " > 0")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count subscriptions"})
+		return
+	}
+
+	var subscriptions []models.ThreadSubscription
+	if err := pagination.Apply(query.Select("thread_subscriptions.*, "+newCommentsSince+" AS new_comments"), "thread_subscriptions", "").
+		Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subscriptions"})
+		return
+	}
+
+	subscriptions, page := paginate(pagination, subscriptions, total, func(s *models.ThreadSubscription) pageCursor {
+		return pageCursor{CreatedAt: s.CreatedAt, ID: s.ID}
+	})
+
+	// Attach the threads in one query
+	postIDs := make([]uint, len(subscriptions))
+	for i := range subscriptions {
+		postIDs[i] = subscriptions[i].PostID
+	}
+	var posts []models.Post
+	if len(postIDs) > 0 {
+		if err := withPostDetails(h.db).Where("id IN ?", postIDs).Find(&posts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subscriptions"})
+			return
+		}
+	}
+	byID := make(map[uint]*models.Post, len(posts))
+	for i := range posts {
+		posts[i].IsSubscribed = true
+		byID[posts[i].ID] = &posts[i]
+	}
+	for i := range subscriptions {
+		subscriptions[i].Post = byID[subscriptions[i].PostID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"pagination":    page,
+	})
+}
+
+// subscribe follows a thread for a user, leaving an existing subscription untouched
+func subscribe(db *gorm.DB, userID, postID uint) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ThreadSubscription{
+		UserID:        userID,
+		PostID:        postID,
+		LastVisitedAt: time.Now(),
+	}).Error
+}
+
+// markThreadVisited records a subscriber's visit and sets the previous one on the post
+// so clients can highlight comments that are new to them
+func markThreadVisited(db *gorm.DB, c *gin.Context, post *models.Post) {
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		return
+	}
+
+	var subscription models.ThreadSubscription
+	if err := db.Where("user_id = ? AND post_id = ?", userID, post.ID).Take(&subscription).Error; err != nil {
+		return
+	}
+
+	previous := subscription.LastVisitedAt
+	post.IsSubscribed = true
+	post.LastVisitedAt = &previous
+	db.Model(&subscription).UpdateColumn("last_visited_at", time.Now())
+}
+
+// markSubscribedPosts sets IsSubscribed on posts followed by the current user, if any
+func markSubscribedPosts(db *gorm.DB, c *gin.Context, posts []models.Post) {
+	userID := c.GetUint("user_id")
+	if userID == 0 || len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	var subscribedIDs []uint
+	db.Model(&models.ThreadSubscription{}).
+		Where("user_id = ? AND post_id IN ?", userID, ids).
+		Pluck("post_id", &subscribedIDs)
+
+	subscribed := make(map[uint]bool, len(subscribedIDs))
+	for _, id := range subscribedIDs {
+		subscribed[id] = true
+	}
+	for i := range posts {
+		posts[i].IsSubscribed = subscribed[posts[i].ID]
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errCommentThrottled rolls back a comment insert that the throttle rejected
+var errCommentThrottled = errors.New("comment throttled")
+
+// commentThrottle explains why a comment was rejected and when the user may try again
+type commentThrottle struct {
+	code       string // 'rate_limited' or 'slow_mode'
+	message    string
+	retryAfter time.Duration
+}
+
+// checkCommentThrottle applies the per-user comment rate and the post's slow mode within
+// the comment-insert transaction tx. Moderators are exempt from both. It returns nil when
+// the comment may be posted.
+func (h *CommentHandler) checkCommentThrottle(c *gin.Context, tx *gorm.DB, userID uint, post *models.Post) (*commentThrottle, error) {
+	if isModerator(c.GetString("role")) {
+		return nil, nil
+	}
+	now := time.Now()
+
+	if post.SlowModeSeconds > 0 {
+		var last []models.Comment
+		if err := tx.Select("created_at").Where("post_id = ? AND user_id = ?", post.ID, userID).
+			Order("created_at DESC").Limit(1).Find(&last).Error; err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			if wait := last[0].CreatedAt.Add(time.Duration(post.SlowModeSeconds) * time.Second).Sub(now); wait > 0 {
+				return &commentThrottle{code: "slow_mode", message: "this thread is in slow mode", retryAfter: wait}, nil
+			}
+		}
+	}
+
+	var user models.User
+	if err := tx.Select("created_at").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	limit := h.cfg.CommentRateLimit
+	if now.Sub(user.CreatedAt) < h.cfg.NewAccountAge {
+		limit = h.cfg.NewAccountCommentRateLimit
+	}
+	if limit <= 0 || h.cfg.CommentRateWindow <= 0 {
+		return nil, nil
+	}
+
+	// The user is at the limit when the limit-th most recent comment is still inside the window
+	var recent []models.Comment
+	if err := tx.Select("created_at").Where("user_id = ? AND created_at > ?", userID, now.Add(-h.cfg.CommentRateWindow)).
+		Order("created_at DESC").Offset(limit - 1).Limit(1).Find(&recent).Error; err != nil {
+		return nil, err
+	}
+	if len(recent) == 0 {
+		return nil, nil
+	}
+	return &commentThrottle{
+		code:       "rate_limited",
+		message:    "you are commenting too quickly",
+		retryAfter: recent[0].CreatedAt.Add(h.cfg.CommentRateWindow).Sub(now),
+	}, nil
+}
+
+// respondThrottled rejects a comment with 429, a Retry-After header and the same hint in the body
+func respondThrottled(c *gin.Context, t *commentThrottle) {
+	seconds := int(math.Ceil(t.retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       t.message,
+		"code":        t.code,
+		"retry_after": seconds,
+	})
+}
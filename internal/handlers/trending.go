@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrendingHandler ranks posts and games by recent activity
+type TrendingHandler struct {
+	db *gorm.DB
+}
+
+// NewTrendingHandler creates a new TrendingHandler
+func NewTrendingHandler(db *gorm.DB) *TrendingHandler {
+	return &TrendingHandler{db: db}
+}
+
+// trendingScore is the aggregated activity of one post or game within the window
+type trendingScore struct {
+	ID       uint    `json:"-"`
+	Score    float64 `json:"score"`
+	Views    int64   `json:"views"`
+	Comments int64   `json:"comments"`
+	Votes    int64   `json:"votes"`
+}
+
+// TrendingPost is a post with its activity in the trending window
+type TrendingPost struct {
+	Post models.Post `json:"post"`
+	trendingScore
+}
+
+// TrendingGame is a game with the activity of its posts in the trending window
+type TrendingGame struct {
+	Game models.Game `json:"game"`
+	trendingScore
+}
+
+// GetTrendingPosts returns the posts with the most activity in the sliding window
+func (h *TrendingHandler) GetTrendingPosts(c *gin.Context) {
+	since, limit := trendingParams(c)
+
+	var scores []trendingScore
+	if err := h.db.Raw("SELECT post_id AS id, "+trendingColumns+" FROM ("+trendingEvents+") AS events "+
+		"GROUP BY post_id ORDER BY score DESC, post_id DESC LIMIT ?", since, since, since, limit).
+		Scan(&scores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute trending posts"})
+		return
+	}
+
+	ids := make([]uint, len(scores))
+	for i, s := range scores {
+		ids[i] = s.ID
+	}
+
+	var posts []models.Post
+	if len(ids) > 0 {
+		if err := withPostDetails(h.db).Where("id IN ?", ids).Find(&posts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
+			return
+		}
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	results := make([]TrendingPost, 0, len(scores))
+	for _, s := range scores {
+		if post, ok := byID[s.ID]; ok {
+			results = append(results, TrendingPost{Post: post, trendingScore: s})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"posts": results, "since": since})
+}
+
+// GetTrendingGames returns the games whose posts have the most activity in the sliding window
+func (h *TrendingHandler) GetTrendingGames(c *gin.Context) {
+	since, limit := trendingParams(c)
+
+	var scores []trendingScore
+	if err := h.db.Raw("SELECT post_games.game_id AS id, "+trendingColumns+" FROM ("+trendingEvents+") AS events "+
+		"JOIN post_games ON post_games.post_id = events.post_id "+
+		"GROUP BY post_games.game_id ORDER BY score DESC, post_games.game_id DESC LIMIT ?", since, since, since, limit).
+		Scan(&scores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute trending games"})
+		return
+	}
+
+	ids := make([]uint, len(scores))
+	for i, s := range scores {
+		ids[i] = s.ID
+	}
+
+	var games []models.Game
+	if len(ids) > 0 {
+		if err := h.db.Preload("Tags").Where("id IN ?", ids).Find(&games).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch games"})
+			return
+		}
+	}
+	byID := make(map[uint]models.Game, len(games))
+	for _, g := range games {
+		byID[g.ID] = g
+	}
+
+	results := make([]TrendingGame, 0, len(scores))
+	for _, s := range scores {
+		if game, ok := byID[s.ID]; ok {
+			results = append(results, TrendingGame{Game: game, trendingScore: s})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"games": results, "since": since})
+}
+
+// trendingEvents lists one row per activity event since the window start, tagged by kind.
+// Comment votes count from their last change, using the indexed comment_votes.updated_at.
+const trendingEvents = "SELECT post_id, 'view' AS kind FROM post_views WHERE viewed_at >= ? " +
+	"UNION ALL SELECT post_id, 'comment' AS kind FROM comments WHERE created_at >= ? " +
+	"UNION ALL SELECT comments.post_id, 'vote' AS kind FROM comment_votes " +
+	"JOIN comments ON comments.id = comment_votes.comment_id WHERE comment_votes.updated_at >= ?"
+
+// trendingColumns aggregates trendingEvents into the score and its breakdown.
+// A comment weighs as much as three views and a vote as much as two.
+const trendingColumns = "SUM(CASE kind WHEN 'comment' THEN 3 WHEN 'vote' THEN 2 ELSE 1 END) AS score, " +
+	"SUM(CASE kind WHEN 'view' THEN 1 ELSE 0 END) AS views, " +
+	"SUM(CASE kind WHEN 'comment' THEN 1 ELSE 0 END) AS comments, " +
+	"SUM(CASE kind WHEN 'vote' THEN 1 ELSE 0 END) AS votes"
+
+// trendingParams reads the sliding window (default 24h, at most 7 days) and result limit
+func trendingParams(c *gin.Context) (time.Time, int) {
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil || window < time.Hour {
+		window = 24 * time.Hour
+	}
+	if window > 7*24*time.Hour {
+		window = 7 * 24 * time.Hour
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	return time.Now().Add(-window), limit
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"forumapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// voteTallies recounts a comment's tallies from its votes, so concurrent votes cannot make them drift
+const voteTallies = "upvotes = (SELECT COUNT(*) FROM comment_votes WHERE comment_id = comments.id AND value = 1), " +
+	"downvotes = (SELECT COUNT(*) FROM comment_votes WHERE comment_id = comments.id AND value = -1)"
+
+// VoteHandler handles up and down votes on comments
+type VoteHandler struct {
+	db *gorm.DB
+}
+
+// NewVoteHandler creates a new VoteHandler
+func NewVoteHandler(db *gorm.DB) *VoteHandler {
+	return &VoteHandler{db: db}
+}
+
+// VoteComment sets the authenticated user's vote on a comment; repeating a vote changes nothing
+func (h *VoteHandler) VoteComment(c *gin.Context) {
+	var req models.VoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setVote(c, *req.Value)
+}
+
+// UnvoteComment clears the authenticated user's vote on a comment
+func (h *VoteHandler) UnvoteComment(c *gin.Context) {
+	h.setVote(c, 0)
+}
+
+// setVote stores the vote and refreshes the comment's denormalized scores in one transaction
+func (h *VoteHandler) setVote(c *gin.Context, value int) {
+	userID := c.GetUint("user_id")
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+	if comment.IsDeleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "deleted comments cannot be voted on"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Single-statement writes keep repeated and concurrent requests idempotent
+		var err error
+		if value == 0 {
+			err = tx.Where("user_id = ? AND comment_id = ?", userID, comment.ID).Delete(&models.CommentVote{}).Error
+		} else {
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "comment_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+			}).Create(&models.CommentVote{UserID: userID, CommentID: comment.ID, Value: value}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return refreshCommentScore(tx, &comment)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment_id": comment.ID,
+		"my_vote":    value,
+		"upvotes":    comment.Upvotes,
+		"downvotes":  comment.Downvotes,
+		"score":      comment.Score,
+	})
+}
+
+// refreshCommentScore recounts a comment's votes and updates its score and best-sort rank
+func refreshCommentScore(tx *gorm.DB, comment *models.Comment) error {
+	if err := tx.Exec("UPDATE comments SET "+voteTallies+" WHERE id = ?", comment.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Select("id", "upvotes", "downvotes").First(comment, comment.ID).Error; err != nil {
+		return err
+	}
+
+	comment.Score = comment.Upvotes - comment.Downvotes
+	comment.BestScore = models.WilsonScore(comment.Upvotes, comment.Downvotes)
+	return tx.Model(&models.Comment{}).Where("id = ?", comment.ID).UpdateColumns(map[string]interface{}{
+		"score":      comment.Score,
+		"best_score": comment.BestScore,
+	}).Error
+}
+
+// attachCommentVotes sets the current user's vote on comments and all their loaded replies
+func attachCommentVotes(db *gorm.DB, c *gin.Context, comments []models.Comment) {
+	userID := c.GetUint("user_id")
+	all := flattenComments(comments)
+	if userID == 0 || len(all) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(all))
+	for i, comment := range all {
+		ids[i] = comment.ID
+	}
+
+	var votes []models.CommentVote
+	db.Select("comment_id", "value").Where("user_id = ? AND comment_id IN ?", userID, ids).Find(&votes)
+
+	byComment := make(map[uint]int, len(votes))
+	for _, vote := range votes {
+		byComment[vote.CommentID] = vote.Value
+	}
+	for _, comment := range all {
+		comment.MyVote = byComment[comment.ID]
+	}
+}
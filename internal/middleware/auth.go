@@ -5,9 +5,11 @@ import (
 	"time"
 
 	"forumapp/internal/config"
+	"forumapp/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 // AuthClaims represents the JWT claims structure
@@ -25,8 +27,9 @@ func SetJWTSecret(cfg *config.Config) {
 	jwtSecret = []byte(cfg.JWTSecret)
 }
 
-// AuthMiddleware validates JWT tokens for protected routes
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens for protected routes and refuses writes by
+// accounts banned in db
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -35,35 +38,84 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Remove "Bearer " if present
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-
-		token, err := jwt.ParseWithClaims(tokenString, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, ok := parseToken(tokenString)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(*AuthClaims); ok {
+		// Tokens outlive a ban, so every write checks the account itself
+		if isWrite(c.Request.Method) {
+			banned, err := isBanned(db, claims.UserID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify account"})
+				c.Abort()
+				return
+			}
+			if banned {
+				c.JSON(http.StatusForbidden, gin.H{"error": "your account is banned"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+
+		c.Next()
+	}
+}
+
+// isWrite reports whether a request method changes state
+func isWrite(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
+
+// isBanned reports whether a user has been banned
+func isBanned(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("id = ? AND is_banned = ?", userID, true).Count(&count).Error
+	return count > 0, err
+}
+
+// OptionalAuthMiddleware sets the user claims when a valid token is present
+// but still lets anonymous requests through
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := parseToken(c.GetHeader("Authorization")); ok {
 			c.Set("user_id", claims.UserID)
 			c.Set("username", claims.Username)
 			c.Set("role", claims.Role)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
-			return
 		}
 
 		c.Next()
 	}
 }
 
+// parseToken validates a bearer token and returns its claims
+func parseToken(tokenString string) (*AuthClaims, bool) {
+	if tokenString == "" {
+		return nil, false
+	}
+
+	// Remove "Bearer " if present
+	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+		tokenString = tokenString[7:]
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(*AuthClaims)
+	return claims, ok
+}
+
 // GenerateJWT creates a new JWT token for a user
 func GenerateJWT(userID uint, username string, role string) (string, error) {
 	claims := AuthClaims{
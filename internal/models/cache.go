@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RAWGCacheEntry is a RAWG API response kept to save API quota
+type RAWGCacheEntry struct {
+	Key        string    `gorm:"primaryKey" json:"key"`      // e.g. 'search:zelda:1:20' or 'game:3498'
+	Kind       string    `gorm:"not null;index" json:"kind"` // 'search' or 'game'
+	Body       []byte    `gorm:"not null" json:"-"`          // The response as JSON
+	FetchedAt  time.Time `gorm:"not null" json:"fetched_at"`
+	FreshUntil time.Time `gorm:"not null" json:"fresh_until"`       // Served as-is until then
+	StaleUntil time.Time `gorm:"not null;index" json:"stale_until"` // Then served while refreshing, until this
+}
@@ -17,8 +17,13 @@ type Game struct {
 	IsLocal     bool      `gorm:"default:false" json:"is_local"` // true if created locally, false if from RAWG
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
-	Tags        []Tag     `gorm:"many2many:game_tags;" json:"tags"`
-	Posts       []Post    `gorm:"foreignKey:GameID" json:"posts,omitempty"`
+	Tags        []Tag     `gorm:"many2many:game_tags;constraint:OnDelete:CASCADE" json:"tags"`
+	Posts       []Post    `gorm:"foreignKey:GameID;constraint:OnDelete:SET NULL" json:"posts,omitempty"`
+
+	// Platforms come from RAWG for imported games and are edited by moderators for local ones
+	Platforms []Platform `gorm:"many2many:game_platforms;constraint:OnDelete:CASCADE" json:"platforms"`
+	// LastSyncedAt is when the RAWG metadata was last refreshed; nil for local games
+	LastSyncedAt *time.Time `gorm:"index" json:"last_synced_at"`
 }
 
 // Tag represents a tag that can be associated with games
@@ -26,23 +31,31 @@ type Tag struct {
 	ID    uint   `gorm:"primaryKey" json:"id"`
 	Name  string `gorm:"unique;not null" json:"name"`
 	Slug  string `gorm:"unique;not null" json:"slug"`
-	Games []Game `gorm:"many2many:game_tags;" json:"games,omitempty"`
+	Games []Game `gorm:"many2many:game_tags;constraint:OnDelete:CASCADE" json:"games,omitempty"`
+}
+
+// Platform is a platform games are available on, such as PC or a console
+type Platform struct {
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Name  string `gorm:"unique;not null" json:"name"`
+	Slug  string `gorm:"unique;not null" json:"slug"`
+	Games []Game `gorm:"many2many:game_platforms;constraint:OnDelete:CASCADE" json:"games,omitempty"`
 }
 
 // RAWGGame represents a game from the RAWG API
 type RAWGGame struct {
-	ID              int        `json:"id"`
-	Name            string     `json:"name"`
-	Slug            string     `json:"slug"`
-	Description     string     `json:"description_raw"`
-	BackgroundImage string     `json:"background_image"`
-	Released        string     `json:"released"`
-	Rating          float64    `json:"rating"`
-	Metacritic      int        `json:"metacritic"`
-	Playtime        int        `json:"playtime"`
-	Genres          []RAWGTag  `json:"genres"`
-	Tags            []RAWGTag  `json:"tags"`
-	Platforms       []Platform `json:"platforms"`
+	ID              int            `json:"id"`
+	Name            string         `json:"name"`
+	Slug            string         `json:"slug"`
+	Description     string         `json:"description_raw"`
+	BackgroundImage string         `json:"background_image"`
+	Released        string         `json:"released"`
+	Rating          float64        `json:"rating"`
+	Metacritic      int            `json:"metacritic"`
+	Playtime        int            `json:"playtime"`
+	Genres          []RAWGTag      `json:"genres"`
+	Tags            []RAWGTag      `json:"tags"`
+	Platforms       []RAWGPlatform `json:"platforms"`
 }
 
 // RAWGTag represents a tag/genre from RAWG API
@@ -52,8 +65,8 @@ type RAWGTag struct {
 	Slug string `json:"slug"`
 }
 
-// Platform represents a platform from RAWG API
-type Platform struct {
+// RAWGPlatform represents a platform entry from RAWG API
+type RAWGPlatform struct {
 	Platform struct {
 		ID   int    `json:"id"`
 		Name string `json:"name"`
@@ -75,5 +88,11 @@ type CreateGameRequest struct {
 	Description string `json:"description"`
 	CoverImage  string `json:"cover_image"`
 	Released    string `json:"released"`
-	Tags        string `json:"tags"` // Comma-separated tags
+	Tags        string `json:"tags"`      // Comma-separated tags
+	Platforms   string `json:"platforms"` // Comma-separated platform names
+}
+
+// SetPlatformsRequest replaces the platforms of a local game
+type SetPlatformsRequest struct {
+	Platforms *string `json:"platforms" binding:"required"` // Comma-separated platform names; empty clears them
 }
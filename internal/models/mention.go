@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Mention records an @username mention of a user in a post or comment
+type Mention struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	MentionedUserID uint      `gorm:"not null;index" json:"mentioned_user_id"`
+	AuthorID        uint      `gorm:"not null" json:"author_id"`
+	TargetType      string    `gorm:"not null;index:idx_mention_target" json:"target_type"` // 'post' or 'comment'
+	TargetID        uint      `gorm:"not null;index:idx_mention_target" json:"target_id"`
+	PostID          uint      `gorm:"not null;index" json:"post_id"` // The post itself, or the post a comment belongs to
+	Start           int       `gorm:"not null" json:"start"`         // Offset of the '@' in the content, in characters
+	End             int       `gorm:"not null" json:"end"`           // Offset just past the username
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// MentionSpan is a resolved mention within a post's or comment's content
+type MentionSpan struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Start    int    `json:"start"` // Character offset of the '@'
+	End      int    `json:"end"`   // Character offset just past the username
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// ModerationAction records an action taken by a moderator for auditing
+type ModerationAction struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ModeratorID uint      `gorm:"not null;index" json:"moderator_id"`
+	Action      string    `gorm:"not null" json:"action"`      // e.g. 'pin', 'lock', 'delete', 'ban', 'dismissed_reports', 'resolved_reports'
+	TargetType  string    `gorm:"not null" json:"target_type"` // 'post', 'comment' or 'user'
+	TargetID    uint      `gorm:"not null" json:"target_id"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+	Moderator   User      `gorm:"foreignKey:ModeratorID" json:"moderator"`
+}
+
+// PinPostRequest represents the request body for pinning a post
+type PinPostRequest struct {
+	Scope  string `json:"scope" binding:"omitempty,oneof=global game"` // Defaults to 'game'
+	Reason string `json:"reason"`
+}
+
+// SlowModeRequest represents the request body for setting a post's slow mode; 0 turns it off
+type SlowModeRequest struct {
+	Seconds *int   `json:"seconds" binding:"required,min=0,max=86400"`
+	Reason  string `json:"reason"`
+}
+
+// ModerationRequest represents an optional reason attached to a moderation action
+type ModerationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Report represents a user's report of a post, comment or user
+type Report struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ReporterID     uint       `gorm:"not null;index" json:"reporter_id"`
+	TargetType     string     `gorm:"not null;index:idx_report_target" json:"target_type"` // 'post', 'comment' or 'user'
+	TargetID       uint       `gorm:"not null;index:idx_report_target" json:"target_id"`
+	Category       string     `gorm:"not null" json:"category"`
+	Details        string     `json:"details"`
+	Status         string     `gorm:"not null;default:open;index" json:"status"` // 'open', 'resolved' or 'dismissed'
+	Outcome        string     `json:"outcome,omitempty"`                         // Action taken on resolution, e.g. 'deleted', 'locked', 'banned'
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	ResolvedByID   *uint      `json:"resolved_by_id,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Reporter       User       `gorm:"foreignKey:ReporterID" json:"reporter"`
+}
+
+// CreateReportRequest represents the request body for reporting content or a user
+type CreateReportRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   uint   `json:"target_id" binding:"required"`
+	Category   string `json:"category" binding:"required,oneof=spam harassment hate nsfw off_topic misinformation other"`
+	Details    string `json:"details" binding:"max=2000"`
+}
+
+// ResolveReportsRequest represents a moderator's decision on the reports of one target
+type ResolveReportsRequest struct {
+	Action string `json:"action" binding:"omitempty,oneof=none delete lock ban"` // Defaults to 'none'
+	Note   string `json:"note"`
+}
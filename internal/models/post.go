@@ -4,35 +4,108 @@ import "time"
 
 // Post represents a forum post
 type Post struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	UserID       uint      `gorm:"not null" json:"user_id"`
-	GameID       *uint     `json:"game_id"` // Nullable for backward compatibility
-	Title        string    `gorm:"not null" json:"title"`
-	Content      string    `gorm:"not null" json:"content"`
-	MediaURL     string    `json:"media_url"`
-	MediaType    string    `json:"media_type"` // 'image' or 'video'
-	GameTag      string    `json:"game_tag"`   // Legacy field for backward compatibility
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	UserID        uint            `gorm:"not null" json:"user_id"`
+	GameID        *uint           `json:"game_id"` // Primary game; nullable for backward compatibility
+	Title         string          `gorm:"not null" json:"title"`
+	Content       string          `gorm:"not null" json:"content"`
+	MediaURL      string          `json:"media_url"`
+	MediaType     string          `json:"media_type"`                    // 'image' or 'video'
+	GameTag       string          `json:"game_tag"`                      // Legacy field for backward compatibility
+	PostType      string          `gorm:"default:text" json:"post_type"` // 'text' or 'link'
+	LinkURL       string          `json:"link_url,omitempty"`
+	IsPinned      bool            `gorm:"default:false" json:"is_pinned"`
+	PinScope      string          `json:"pin_scope,omitempty"` // 'global' or 'game' when pinned
+	IsLocked      bool            `gorm:"default:false" json:"is_locked"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	User          User            `gorm:"foreignKey:UserID" json:"user"`
+	Game          *Game           `gorm:"foreignKey:GameID" json:"game,omitempty"`
+	Games         []Game          `gorm:"many2many:post_games;constraint:OnDelete:CASCADE" json:"games"` // All linked games, including the primary one
+	LinkPreview   *LinkPreview    `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"link_preview,omitempty"`
+	Comments      []Comment       `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"comments,omitempty"`
+	ViewCount     int             `gorm:"not null;default:0" json:"view_count"`    // Deduplicated views, flushed in batches
+	CommentCount  int             `gorm:"not null;default:0" json:"comment_count"` // Denormalized, kept in sync on comment create/delete
+	IsSaved       bool            `gorm:"-" json:"is_saved"`                       // Whether the current user bookmarked this post
+	IsSubscribed  bool            `gorm:"-" json:"is_subscribed"`                  // Whether the current user follows this thread
+	LastVisitedAt *time.Time      `gorm:"-" json:"last_visited_at,omitempty"`      // The subscriber's previous visit; newer comments are unread
+	Reactions     []ReactionCount `gorm:"-" json:"reactions,omitempty"`            // Aggregated reactions, with the viewer's own flagged
+	Mentions      []MentionSpan   `gorm:"-" json:"mentions,omitempty"`             // Resolved @username mentions in the content
+
+	AcceptedCommentID  *uint                `gorm:"index" json:"accepted_comment_id"`            // Answer chosen by the author or a moderator
+	SlowModeSeconds    int                  `gorm:"not null;default:0" json:"slow_mode_seconds"` // Minimum gap between one user's comments, set by moderators
+	AcceptedAnswer     *Comment             `gorm:"-" json:"accepted_answer,omitempty"`          // The accepted comment, in listings and post responses
+	PossibleDuplicates []DuplicateCandidate `gorm:"-" json:"possible_duplicates,omitempty"`      // Only set when creating a post
+	Views              []PostView           `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+	Subscriptions      []ThreadSubscription `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DuplicateCandidate is a recent post in the same game that looks like the one being written
+type DuplicateCandidate struct {
+	ID           uint      `json:"id"`
+	Title        string    `json:"title"`
 	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	User         User      `gorm:"foreignKey:UserID" json:"user"`
-	Game         *Game     `gorm:"foreignKey:GameID" json:"game,omitempty"`
-	Comments     []Comment `gorm:"foreignKey:PostID" json:"comments,omitempty"`
-	CommentCount int       `gorm:"-" json:"comment_count"` // Not stored in DB, calculated
+	CommentCount int       `json:"comment_count"`
+	Similarity   float64   `json:"similarity"` // 0-1, from normalized title and content overlap
+	Exact        bool      `json:"exact"`      // Same normalized title and content (or link)
+}
+
+// LinkPreview stores the OpenGraph/oEmbed metadata fetched for a link post
+type LinkPreview struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	PostID      uint      `gorm:"not null;uniqueIndex" json:"-"`
+	URL         string    `gorm:"not null" json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url"`
+	SiteName    string    `json:"site_name"`
+	Provider    string    `json:"provider,omitempty"`   // Set for known video hosts
+	EmbedHTML   string    `json:"embed_html,omitempty"` // Player markup from a trusted oEmbed provider
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// PostView records that a viewer saw a post; each viewer counts once per window
+type PostView struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PostID      uint      `gorm:"not null;uniqueIndex:idx_post_view" json:"post_id"`
+	ViewerKey   string    `gorm:"not null;uniqueIndex:idx_post_view" json:"-"` // 'u:<id>' or 'a:<fingerprint>'
+	WindowStart time.Time `gorm:"not null;uniqueIndex:idx_post_view" json:"-"`
+	ViewedAt    time.Time `gorm:"not null;index" json:"viewed_at"`
 }
 
 // Comment represents a comment on a post with support for nested replies (Reddit-style)
 type Comment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	PostID    uint      `gorm:"not null" json:"post_id"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	ParentID  *uint     `json:"parent_id"` // nil for top-level comments, set for replies
-	Content   string    `gorm:"not null" json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"user"`
-	Post      Post      `gorm:"foreignKey:PostID" json:"-"`
-	Parent    *Comment  `gorm:"foreignKey:ParentID" json:"-"`
-	Replies   []Comment `gorm:"foreignKey:ParentID" json:"replies,omitempty"`
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	PostID        uint       `gorm:"not null;index" json:"post_id"`
+	UserID        uint       `gorm:"not null;index:idx_comments_user_created" json:"user_id"`
+	ParentID      *uint      `gorm:"index" json:"parent_id"` // nil for top-level comments, set for replies
+	Content       string     `gorm:"not null" json:"content"`
+	ReplyCount    int        `gorm:"not null;default:0" json:"reply_count"` // Denormalized count of direct replies
+	Upvotes       int        `gorm:"not null;default:0" json:"upvotes"`     // Vote tallies used by the top and controversial sorts
+	Downvotes     int        `gorm:"not null;default:0" json:"downvotes"`
+	Score         int        `gorm:"not null;default:0;index" json:"score"`    // Upvotes minus downvotes
+	BestScore     float64    `gorm:"not null;default:0" json:"-"`              // Wilson lower bound of the upvote ratio, used by the best sort
+	IsDeleted     bool       `gorm:"not null;default:false" json:"is_deleted"` // Tombstone kept so replies stay in place
+	RemovedByID   *uint      `json:"-"`                                        // Who deleted the comment; the author or a moderator
+	RemovalReason string     `json:"removal_reason,omitempty"`
+	RemovedAt     *time.Time `json:"removed_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"index:idx_comments_user_created" json:"created_at"` // With UserID, indexes the per-user comment rate check
+	UpdatedAt     time.Time  `json:"updated_at"`
+	User          User       `gorm:"foreignKey:UserID" json:"user"`
+	Post          Post       `gorm:"foreignKey:PostID" json:"-"`
+	Parent        *Comment   `gorm:"foreignKey:ParentID" json:"-"`
+	Replies       []Comment  `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"replies,omitempty"`
+	Depth         int        `gorm:"->;-:migration" json:"depth"` // Level below the requested comments when loaded as a tree
+
+	HasMoreReplies    bool            `gorm:"-" json:"has_more_replies,omitempty"`    // Not all replies were returned
+	ContinueThreadURL string          `gorm:"-" json:"continue_thread_url,omitempty"` // Where to load replies cut off by the depth limit
+	MoreRepliesURL    string          `gorm:"-" json:"more_replies_url,omitempty"`    // Where to load replies cut off by the per-comment limit
+	Reactions         []ReactionCount `gorm:"-" json:"reactions,omitempty"`           // Aggregated reactions, with the viewer's own flagged
+	Mentions          []MentionSpan   `gorm:"-" json:"mentions,omitempty"`            // Resolved @username mentions in the content
+	MyVote            int             `gorm:"-" json:"my_vote"`                       // The current user's vote: 1, -1 or 0
+	IsAccepted        bool            `gorm:"-" json:"is_accepted"`                   // Marked as the post's accepted answer
+	PinnedAbove       bool            `gorm:"-" json:"pinned_above,omitempty"`        // A reply standing in for the accepted answer pinned first in the listing
+	Votes             []CommentVote   `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 // CreatePostRequest represents the request body for creating a post
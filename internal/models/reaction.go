@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Reaction is a user's emoji reaction to a post or comment
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_reaction" json:"user_id"`
+	TargetType string    `gorm:"not null;uniqueIndex:idx_reaction;index:idx_reaction_target" json:"target_type"` // 'post' or 'comment'
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_reaction;index:idx_reaction_target" json:"target_id"`
+	Emoji      string    `gorm:"not null;uniqueIndex:idx_reaction" json:"emoji"` // Unicode emoji or ':shortcode:' of a custom reaction
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CustomReaction is an image reaction a moderator uploaded for a single game
+type CustomReaction struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	GameID      uint      `gorm:"not null;uniqueIndex:idx_custom_reaction" json:"game_id"`
+	Shortcode   string    `gorm:"not null;uniqueIndex:idx_custom_reaction" json:"shortcode"` // Used as ':shortcode:' when reacting
+	ImageURL    string    `gorm:"not null" json:"image_url"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReactionCount is the aggregated count of one reaction on a post or comment
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"` // Whether the current user added this reaction
+}
+
+// ReactRequest represents the request body for toggling a reaction
+type ReactRequest struct {
+	Emoji string `json:"emoji" binding:"required,max=64"`
+}
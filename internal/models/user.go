@@ -4,10 +4,40 @@ import "time"
 
 // User represents a forum user
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"unique;not null" json:"username"`
-	Email     string    `gorm:"unique;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	Role      string    `gorm:"default:user" json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Username  string     `gorm:"unique;not null" json:"username"`
+	Email     string     `gorm:"unique;not null" json:"email"`
+	Password  string     `gorm:"not null" json:"-"`
+	Role      string     `gorm:"default:user" json:"role"`
+	IsBanned  bool       `gorm:"default:false" json:"is_banned"`
+	BannedAt  *time.Time `json:"banned_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// SavedItem represents a post or comment bookmarked by a user
+type SavedItem struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_saved_target" json:"user_id"`
+	TargetType string    `gorm:"not null;uniqueIndex:idx_saved_target" json:"target_type"` // 'post' or 'comment'
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_saved_target" json:"target_id"`
+	Folder     string    `gorm:"index" json:"folder"` // Optional collection name, empty for unsorted
+	CreatedAt  time.Time `json:"created_at"`
+	Post       *Post     `gorm:"-" json:"post,omitempty"`
+	Comment    *Comment  `gorm:"-" json:"comment,omitempty"`
+}
+
+// SaveItemRequest represents the optional request body for saving a post or comment
+type SaveItemRequest struct {
+	Folder string `json:"folder" binding:"max=64"`
+}
+
+// ThreadSubscription represents a user following a post's discussion
+type ThreadSubscription struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_thread_subscription" json:"user_id"`
+	PostID        uint      `gorm:"not null;uniqueIndex:idx_thread_subscription;index" json:"post_id"`
+	LastVisitedAt time.Time `gorm:"not null" json:"last_visited_at"` // Comments after this are unread
+	CreatedAt     time.Time `json:"created_at"`
+	NewComments   int64     `gorm:"->;-:migration" json:"new_comments"` // Comments by others since the last visit, when listing
+	Post          *Post     `gorm:"-" json:"post,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// CommentVote is a user's up (+1) or down (-1) vote on a comment
+type CommentVote struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_comment_vote" json:"user_id"`
+	CommentID uint      `gorm:"not null;uniqueIndex:idx_comment_vote;index" json:"comment_id"`
+	Value     int       `gorm:"not null" json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
+
+// VoteRequest represents the request body for voting on a comment; 0 clears the vote
+type VoteRequest struct {
+	Value *int `json:"value" binding:"required,oneof=-1 0 1"`
+}
+
+// WilsonScore returns the lower bound of the 95% Wilson confidence interval for the
+// share of upvotes, so a comment with 40 of 50 upvotes ranks above one with 1 of 1
+func WilsonScore(upvotes, downvotes int) float64 {
+	n := float64(upvotes + downvotes)
+	if n == 0 {
+		return 0
+	}
+
+	const z = 1.96
+	p := float64(upvotes) / n
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
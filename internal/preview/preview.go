@@ -0,0 +1,392 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// ErrUnsupportedURL is returned for URLs that are not absolute http(s) links
+	ErrUnsupportedURL = errors.New("only http and https links are supported")
+	// ErrBlockedAddress is returned when a link resolves to a private or local address
+	ErrBlockedAddress = errors.New("link points to a private or local address")
+)
+
+// Preview holds the metadata shown for a link post
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+	Provider    string `json:"provider"`   // oEmbed provider name for known video hosts
+	EmbedHTML   string `json:"embed_html"` // Only set for known video hosts
+}
+
+// Provider is an oEmbed provider whose embed HTML we trust
+type Provider struct {
+	Name     string
+	Hosts    []string
+	Endpoint string // oEmbed endpoint; the link is passed as the url parameter
+}
+
+// DefaultProviders are the known video hosts we embed players for
+var DefaultProviders = []Provider{
+	{Name: "YouTube", Hosts: []string{"youtube.com", "www.youtube.com", "m.youtube.com", "youtu.be"}, Endpoint: "https://www.youtube.com/oembed"},
+	{Name: "Vimeo", Hosts: []string{"vimeo.com", "www.vimeo.com", "player.vimeo.com"}, Endpoint: "https://vimeo.com/api/oembed.json"},
+}
+
+// Options configures a Fetcher
+type Options struct {
+	Timeout      time.Duration // Per-fetch timeout, including redirects
+	MaxBytes     int64         // Maximum bytes read from any response body
+	CacheTTL     time.Duration // How long previews are reused for the same URL
+	AllowPrivate bool          // Allow private and loopback addresses; only for tests
+	Providers    []Provider    // Defaults to DefaultProviders
+}
+
+// Fetcher retrieves OpenGraph and oEmbed metadata for links
+type Fetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	cacheTTL  time.Duration
+	providers []Provider
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	preview   *Preview
+	expiresAt time.Time
+}
+
+// maxCacheEntries bounds the in-memory cache; expired entries are evicted first
+const maxCacheEntries = 1000
+
+// NewFetcher creates a Fetcher with SSRF protection on every outgoing connection
+func NewFetcher(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 20
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Hour
+	}
+	if opts.Providers == nil {
+		opts.Providers = DefaultProviders
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		// Checking at dial time covers redirects and DNS rebinding
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isBlockedIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return errors.New("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrUnsupportedURL
+				}
+				return nil
+			},
+		},
+		maxBytes:  opts.MaxBytes,
+		cacheTTL:  opts.CacheTTL,
+		providers: opts.Providers,
+		cache:     make(map[string]cacheEntry),
+	}
+}
+
+// isBlockedIP reports whether an address is private, loopback or otherwise internal
+func isBlockedIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		// "This network" and carrier-grade NAT ranges
+		(ip.To4() != nil && (ip.To4()[0] == 0 || (ip.To4()[0] == 100 && ip.To4()[1]&0xc0 == 64)))
+}
+
+// ValidateURL checks that a link is an absolute http(s) URL and returns it normalized
+func ValidateURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrUnsupportedURL
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// Fetch returns the preview for a link, using the cache when possible
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	link, err := ValidateURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached := f.cached(link); cached != nil {
+		return cached, nil
+	}
+
+	u, _ := url.Parse(link)
+	var p *Preview
+	if provider := f.providerFor(u.Hostname()); provider != nil {
+		p, err = f.fetchOEmbed(ctx, provider, link)
+	} else {
+		p, err = f.fetchOpenGraph(ctx, link)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.store(link, p)
+	return p, nil
+}
+
+// providerFor returns the trusted oEmbed provider for a host, if any
+func (f *Fetcher) providerFor(host string) *Provider {
+	host = strings.ToLower(host)
+	for i := range f.providers {
+		for _, h := range f.providers[i].Hosts {
+			if host == h {
+				return &f.providers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// fetchOEmbed asks a trusted provider for embed metadata
+func (f *Fetcher) fetchOEmbed(ctx context.Context, provider *Provider, link string) (*Preview, error) {
+	endpoint, err := url.Parse(provider.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := endpoint.Query()
+	q.Set("url", link)
+	q.Set("format", "json")
+	endpoint.RawQuery = q.Encode()
+
+	body, _, err := f.get(ctx, endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Title        string `json:"title"`
+		AuthorName   string `json:"author_name"`
+		ProviderName string `json:"provider_name"`
+		ThumbnailURL string `json:"thumbnail_url"`
+		HTML         string `json:"html"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("invalid oEmbed response: %w", err)
+	}
+
+	siteName := data.ProviderName
+	if siteName == "" {
+		siteName = provider.Name
+	}
+
+	return &Preview{
+		URL:         link,
+		Title:       data.Title,
+		Description: data.AuthorName,
+		ImageURL:    data.ThumbnailURL,
+		SiteName:    siteName,
+		Provider:    provider.Name,
+		EmbedHTML:   data.HTML,
+	}, nil
+}
+
+// fetchOpenGraph reads OpenGraph and standard meta tags from an HTML page
+func (f *Fetcher) fetchOpenGraph(ctx context.Context, link string) (*Preview, error) {
+	body, finalURL, err := f.get(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Preview{URL: link}
+	var title string
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+tokens:
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		switch {
+		case tt == html.StartTagToken && token.Data == "title":
+			inTitle = true
+		case tt == html.TextToken && inTitle && title == "":
+			title = strings.TrimSpace(token.Data)
+		case tt == html.EndTagToken && token.Data == "title":
+			inTitle = false
+		case (tt == html.StartTagToken || tt == html.SelfClosingTagToken) && token.Data == "meta":
+			applyMeta(p, token)
+		case tt == html.EndTagToken && token.Data == "head":
+			// Metadata lives in the head; skip the rest of the page
+			break tokens
+		}
+	}
+
+	if p.Title == "" {
+		p.Title = title
+	}
+	if p.ImageURL != "" {
+		p.ImageURL = resolveURL(finalURL, p.ImageURL)
+	}
+	return p, nil
+}
+
+// applyMeta copies a recognised meta tag into the preview, preferring OpenGraph values
+func applyMeta(p *Preview, token html.Token) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "property", "name":
+			key = strings.ToLower(attr.Val)
+		case "content":
+			content = strings.TrimSpace(attr.Val)
+		}
+	}
+	if content == "" {
+		return
+	}
+
+	switch key {
+	case "og:title":
+		p.Title = content
+	case "og:description":
+		p.Description = content
+	case "description":
+		if p.Description == "" {
+			p.Description = content
+		}
+	case "og:image", "og:image:url":
+		if p.ImageURL == "" {
+			p.ImageURL = content
+		}
+	case "og:site_name":
+		p.SiteName = content
+	}
+}
+
+// resolveURL resolves a possibly relative reference against the page URL
+func resolveURL(base *url.URL, ref string) string {
+	r, err := url.Parse(ref)
+	if err != nil || base == nil {
+		return ref
+	}
+	return base.ResolveReference(r).String()
+}
+
+// get performs a GET request, reading at most maxBytes of the body
+func (f *Fetcher) get(ctx context.Context, link string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "forumapp-link-preview/1.0")
+	req.Header.Set("Accept", "text/html,application/json;q=0.9,*/*;q=0.5")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return nil, nil, ErrBlockedAddress
+		}
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("link returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// cached returns an unexpired cached preview
+func (f *Fetcher) cached(link string) *Preview {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.cache[link]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	p := *entry.preview
+	return &p
+}
+
+// store caches a preview, evicting expired entries when the cache is full
+func (f *Fetcher) store(link string, p *Preview) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if len(f.cache) >= maxCacheEntries {
+		for key, entry := range f.cache {
+			if now.After(entry.expiresAt) {
+				delete(f.cache, key)
+			}
+		}
+		// Still full: drop an arbitrary entry
+		for key := range f.cache {
+			if len(f.cache) < maxCacheEntries {
+				break
+			}
+			delete(f.cache, key)
+		}
+	}
+
+	stored := *p
+	f.cache[link] = cacheEntry{preview: &stored, expiresAt: now.Add(f.cacheTTL)}
+}
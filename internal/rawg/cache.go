@@ -0,0 +1,265 @@
+package rawg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"forumapp/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CacheOptions configures a CachedClient
+type CacheOptions struct {
+	SearchTTL      time.Duration // How long search results are fresh
+	GameTTL        time.Duration // How long game details are fresh
+	StaleFor       time.Duration // How long past its TTL a response is still served while it is refreshed
+	RefreshTimeout time.Duration // Bound on background refreshes
+	PruneInterval  time.Duration // How often writes also delete entries past their stale period
+}
+
+// CacheStats counts cache outcomes since startup, with the number of stored entries
+type CacheStats struct {
+	Hits          int64 `json:"hits"`
+	StaleHits     int64 `json:"stale_hits"` // Served stale while refreshing in the background
+	Misses        int64 `json:"misses"`
+	Coalesced     int64 `json:"coalesced"` // Misses that waited on an identical request in flight
+	RefreshErrors int64 `json:"refresh_errors"`
+	Entries       int64 `json:"entries"`
+}
+
+// CachedClient is a Client that keeps responses in the database. Fresh entries are served
+// directly, stale ones are served while a background refresh runs, and identical misses
+// in flight at the same time share one upstream request.
+type CachedClient struct {
+	db       *gorm.DB
+	upstream Client
+	opts     CacheOptions
+	flights  flightGroup
+
+	hits, staleHits, misses, coalesced, refreshErrors atomic.Int64
+	lastPrune                                         atomic.Int64 // Unix nanoseconds of the last prune
+}
+
+// NewCachedClient wraps upstream with a cache stored in db
+func NewCachedClient(db *gorm.DB, upstream Client, opts CacheOptions) *CachedClient {
+	if opts.SearchTTL <= 0 {
+		opts.SearchTTL = time.Hour
+	}
+	if opts.GameTTL <= 0 {
+		opts.GameTTL = 24 * time.Hour
+	}
+	if opts.StaleFor <= 0 {
+		opts.StaleFor = 24 * time.Hour
+	}
+	if opts.RefreshTimeout <= 0 {
+		opts.RefreshTimeout = 30 * time.Second
+	}
+	if opts.PruneInterval <= 0 {
+		opts.PruneInterval = 10 * time.Minute
+	}
+
+	return &CachedClient{db: db, upstream: upstream, opts: opts}
+}
+
+// SearchGames returns cached search results, keyed by the normalized query and page
+func (c *CachedClient) SearchGames(ctx context.Context, query string, page, pageSize int) (*models.RAWGSearchResponse, error) {
+	key := fmt.Sprintf("search:%s:%d:%d", strings.Join(strings.Fields(strings.ToLower(query)), " "), page, pageSize)
+	return lookup(c, ctx, "search", key, c.opts.SearchTTL, func(ctx context.Context) (*models.RAWGSearchResponse, error) {
+		return c.upstream.SearchGames(ctx, query, page, pageSize)
+	})
+}
+
+// GetGame returns cached game details
+func (c *CachedClient) GetGame(ctx context.Context, idOrSlug string) (*models.RAWGGame, error) {
+	key := "game:" + strings.ToLower(idOrSlug)
+	return lookup(c, ctx, "game", key, c.opts.GameTTL, func(ctx context.Context) (*models.RAWGGame, error) {
+		return c.upstream.GetGame(ctx, idOrSlug)
+	})
+}
+
+// Stats returns the cache counters and the number of stored entries
+func (c *CachedClient) Stats() (CacheStats, error) {
+	stats := CacheStats{
+		Hits:          c.hits.Load(),
+		StaleHits:     c.staleHits.Load(),
+		Misses:        c.misses.Load(),
+		Coalesced:     c.coalesced.Load(),
+		RefreshErrors: c.refreshErrors.Load(),
+	}
+	err := c.db.Model(&models.RAWGCacheEntry{}).Count(&stats.Entries).Error
+	return stats, err
+}
+
+// Purge deletes cached entries of a kind ('search' or 'game'), or all of them when kind
+// is empty. With expiredOnly, entries still fresh or servable stale are kept.
+func (c *CachedClient) Purge(kind string, expiredOnly bool) (int64, error) {
+	query := c.db.Where("1 = 1")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if expiredOnly {
+		query = query.Where("stale_until <= ?", time.Now())
+	}
+	result := query.Delete(&models.RAWGCacheEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// lookup serves key from the cache or fetches it upstream. Every caller decodes its own
+// copy of the response, so shared results are never mutated concurrently.
+func lookup[T any](c *CachedClient, ctx context.Context, kind, key string, ttl time.Duration, fetch func(context.Context) (*T, error)) (*T, error) {
+	load := func(ctx context.Context) ([]byte, error) {
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		c.store(kind, key, body, ttl)
+		return body, nil
+	}
+
+	var entry models.RAWGCacheEntry
+	found := c.db.Where("key = ?", key).Limit(1).Find(&entry).RowsAffected > 0
+	now := time.Now()
+
+	var body []byte
+	switch {
+	case found && now.Before(entry.FreshUntil):
+		c.hits.Add(1)
+		body = entry.Body
+	case found && now.Before(entry.StaleUntil):
+		c.staleHits.Add(1)
+		c.refresh(key, load)
+		body = entry.Body
+	default:
+		c.misses.Add(1)
+		// A caller going away must not fail the others waiting on the same request
+		var err error
+		var shared bool
+		body, shared, err = c.flights.do(ctx, key, func() ([]byte, error) {
+			return load(context.WithoutCancel(ctx))
+		})
+		if shared {
+			c.coalesced.Add(1)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var value T
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// refresh reloads a stale entry in the background unless a request for it is in flight
+func (c *CachedClient) refresh(key string, load func(context.Context) ([]byte, error)) {
+	if c.flights.busy(key) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.RefreshTimeout)
+		defer cancel()
+		if _, _, err := c.flights.do(ctx, key, func() ([]byte, error) { return load(ctx) }); err != nil {
+			c.refreshErrors.Add(1)
+			log.Printf("failed to refresh RAWG cache entry %s: %v", key, err)
+		}
+	}()
+}
+
+// store saves a response, replacing any previous entry for the key
+func (c *CachedClient) store(kind, key string, body []byte, ttl time.Duration) {
+	now := time.Now()
+	entry := models.RAWGCacheEntry{
+		Key:        key,
+		Kind:       kind,
+		Body:       body,
+		FetchedAt:  now,
+		FreshUntil: now.Add(ttl),
+		StaleUntil: now.Add(ttl + c.opts.StaleFor),
+	}
+	if err := c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error; err != nil {
+		log.Printf("failed to store RAWG cache entry %s: %v", key, err)
+	}
+	c.pruneExpired(now)
+}
+
+// pruneExpired deletes entries past their stale period, at most once per PruneInterval
+func (c *CachedClient) pruneExpired(now time.Time) {
+	last := c.lastPrune.Load()
+	if now.UnixNano()-last < int64(c.opts.PruneInterval) || !c.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	if _, err := c.Purge("", true); err != nil {
+		log.Printf("failed to prune expired RAWG cache entries: %v", err)
+	}
+}
+
+// flightGroup runs one call per key at a time and shares its result with every caller
+// that asks for the same key while it is running
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// errFlightAborted is what callers waiting on a call see when it panicked
+var errFlightAborted = errors.New("shared RAWG request was aborted")
+
+// do runs fn for key, or waits for the call already running until ctx is done. shared
+// reports whether the result came from another caller's call.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) (body []byte, shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.body, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	call := &flight{done: make(chan struct{}), err: errFlightAborted}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// Waiters are released even if fn panics, and then see errFlightAborted
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.body, call.err = fn()
+	return call.body, false, call.err
+}
+
+// busy reports whether a call for key is running
+func (g *flightGroup) busy(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
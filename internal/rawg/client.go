@@ -0,0 +1,228 @@
+// Package rawg is a client for the RAWG video game database API
+package rawg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"forumapp/internal/models"
+)
+
+// DefaultBaseURL is the public RAWG API
+const DefaultBaseURL = "https://api.rawg.io/api"
+
+var (
+	// ErrNotFound is returned when RAWG has no game with the requested ID or slug
+	ErrNotFound = errors.New("game not found on RAWG")
+	// ErrRateLimited is returned when RAWG keeps rejecting requests with 429 after all retries
+	ErrRateLimited = errors.New("RAWG rate limit exceeded")
+	// ErrUnavailable is returned when RAWG cannot be reached or answers with an error
+	ErrUnavailable = errors.New("RAWG is unavailable")
+)
+
+// Error describes a failed RAWG request. It wraps one of the sentinel errors so callers
+// can match it with errors.Is, while the details stay available for logging.
+type Error struct {
+	Op         string // 'search' or 'game'
+	StatusCode int    // Upstream HTTP status, 0 when no response was received
+	Err        error  // ErrNotFound, ErrRateLimited or ErrUnavailable
+	Cause      error  // Underlying transport or decoding error, if any
+}
+
+func (e *Error) Error() string {
+	msg := "rawg " + e.Op + ": " + e.Err.Error()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+// temporary reports whether retrying the request may succeed: rate limiting, server
+// errors and failures before any response was received
+func (e *Error) temporary() bool {
+	return e.Err == ErrRateLimited || (e.Err == ErrUnavailable && (e.StatusCode == 0 || e.StatusCode >= 500))
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// Client is the part of the RAWG API the forum uses
+type Client interface {
+	SearchGames(ctx context.Context, query string, page, pageSize int) (*models.RAWGSearchResponse, error)
+	GetGame(ctx context.Context, idOrSlug string) (*models.RAWGGame, error)
+}
+
+// Options configures an HTTPClient
+type Options struct {
+	BaseURL    string        // Defaults to DefaultBaseURL
+	APIKey     string        // Sent as the key query parameter
+	Timeout    time.Duration // Per-attempt timeout, on top of the caller's context
+	MaxRetries int           // Retries after a 429, 5xx or transport error; negative disables them
+	Backoff    time.Duration // Delay before the first retry, doubled for each further one
+	MaxBackoff time.Duration // Upper bound on any single delay, including Retry-After
+	HTTPClient *http.Client  // Defaults to a client without its own timeout
+}
+
+// HTTPClient calls the RAWG HTTP API
+type HTTPClient struct {
+	baseURL    string
+	apiKey     string
+	timeout    time.Duration
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	http       *http.Client
+}
+
+// maxResponseBytes bounds how much of a RAWG response is read
+const maxResponseBytes = 4 << 20
+
+// New creates an HTTPClient
+func New(opts Options) *HTTPClient {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 2
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Second
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{}
+	}
+
+	return &HTTPClient{
+		baseURL:    strings.TrimRight(opts.BaseURL, "/"),
+		apiKey:     opts.APIKey,
+		timeout:    opts.Timeout,
+		maxRetries: opts.MaxRetries,
+		backoff:    opts.Backoff,
+		maxBackoff: opts.MaxBackoff,
+		http:       opts.HTTPClient,
+	}
+}
+
+// SearchGames searches RAWG by name
+func (c *HTTPClient) SearchGames(ctx context.Context, query string, page, pageSize int) (*models.RAWGSearchResponse, error) {
+	params := url.Values{
+		"search":    {query},
+		"page":      {strconv.Itoa(page)},
+		"page_size": {strconv.Itoa(pageSize)},
+	}
+
+	var data models.RAWGSearchResponse
+	if err := c.get(ctx, "search", "/games", params, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetGame fetches a game's details by RAWG ID or slug
+func (c *HTTPClient) GetGame(ctx context.Context, idOrSlug string) (*models.RAWGGame, error) {
+	var game models.RAWGGame
+	if err := c.get(ctx, "game", "/games/"+url.PathEscape(idOrSlug), nil, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// get performs a GET request with retries and decodes the JSON response into out
+func (c *HTTPClient) get(ctx context.Context, op, path string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("key", c.apiKey)
+	endpoint := c.baseURL + path + "?" + params.Encode()
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.attempt(ctx, endpoint, out)
+		if err == nil {
+			return nil
+		}
+		err.Op = op
+		if !err.temporary() || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := c.backoff << attempt
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		if delay > c.maxBackoff {
+			delay = c.maxBackoff
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return &Error{Op: op, StatusCode: err.StatusCode, Err: ErrUnavailable, Cause: ctx.Err()}
+		}
+	}
+}
+
+// attempt makes a single request bounded by the per-attempt timeout. On a 429 it also
+// returns the delay asked for by the Retry-After header.
+func (c *HTTPClient) attempt(ctx context.Context, endpoint string, out interface{}) (time.Duration, *Error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, &Error{Err: ErrUnavailable, Cause: err}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, &Error{Err: ErrUnavailable, Cause: err}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(out); err != nil {
+			return 0, &Error{StatusCode: resp.StatusCode, Err: ErrUnavailable, Cause: err}
+		}
+		return 0, nil
+	case http.StatusNotFound:
+		return 0, &Error{StatusCode: resp.StatusCode, Err: ErrNotFound}
+	case http.StatusTooManyRequests:
+		return parseRetryAfter(resp.Header.Get("Retry-After")), &Error{StatusCode: resp.StatusCode, Err: ErrRateLimited}
+	default:
+		return 0, &Error{StatusCode: resp.StatusCode, Err: ErrUnavailable}
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
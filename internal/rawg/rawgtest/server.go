@@ -0,0 +1,136 @@
+// Package rawgtest provides an in-memory fake of the RAWG API for offline tests
+package rawgtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"forumapp/internal/models"
+)
+
+// Server is a fake RAWG API serving a fixed set of games. Point a client's base URL at
+// URL to use it.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	games    []models.RAWGGame
+	failures []int // Statuses returned, in order, before serving normally again
+	requests int
+}
+
+// NewServer starts a fake RAWG API serving games
+func NewServer(games ...models.RAWGGame) *Server {
+	s := &Server{games: append([]models.RAWGGame(nil), games...)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /games", s.search)
+	mux.HandleFunc("GET /games/{id}", s.game)
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// AddGame adds or replaces a game, matched by ID
+func (s *Server) AddGame(game models.RAWGGame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.games {
+		if s.games[i].ID == game.ID {
+			s.games[i] = game
+			return
+		}
+	}
+	s.games = append(s.games, game)
+}
+
+// FailNext makes the next requests fail with the given statuses, one per request
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests returns how many requests the server has received
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// intercept counts requests, requires an API key and applies queued failures
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var status int
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		switch {
+		case r.URL.Query().Get("key") == "":
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "The key parameter is not provided"})
+		case status == http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, status, map[string]string{"error": "rate limited"})
+		case status != 0:
+			writeJSON(w, status, map[string]string{"error": "upstream failure: internal trace 0xdeadbeef"})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// search serves /games?search=, matching names case-insensitively
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("search"))
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	s.mu.Lock()
+	var matches []models.RAWGGame
+	for _, game := range s.games {
+		if strings.Contains(strings.ToLower(game.Name), query) {
+			matches = append(matches, game)
+		}
+	}
+	s.mu.Unlock()
+
+	resp := models.RAWGSearchResponse{Count: len(matches), Results: []models.RAWGGame{}}
+	start := min((page-1)*pageSize, len(matches))
+	end := min(start+pageSize, len(matches))
+	resp.Results = append(resp.Results, matches[start:end]...)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// game serves /games/{id} by ID or slug
+func (s *Server) game(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, game := range s.games {
+		if strconv.Itoa(game.ID) == id || game.Slug == id {
+			writeJSON(w, http.StatusOK, game)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
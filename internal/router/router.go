@@ -4,16 +4,22 @@ import (
 	"os"
 
 	"forumapp/internal/config"
+	"forumapp/internal/events"
+	"forumapp/internal/gamesync"
 	"forumapp/internal/handlers"
 	"forumapp/internal/middleware"
+	"forumapp/internal/preview"
+	"forumapp/internal/rawg"
+	"forumapp/internal/views"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// Setup configures and returns the Gin router
-func Setup(db *gorm.DB, cfg *config.Config) *gin.Engine {
+// Setup configures and returns the Gin router, along with a function that stops its
+// background jobs and flushes any buffered post views
+func Setup(db *gorm.DB, cfg *config.Config, eventBus *events.Bus) (*gin.Engine, func()) {
 	router := gin.Default()
 	router.Use(cors.Default())
 
@@ -28,10 +34,44 @@ func Setup(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, cfg)
-	postHandler := handlers.NewPostHandler(db)
-	gameHandler := handlers.NewGameHandler(db, cfg)
+	// Post views are buffered in memory and flushed in the background
+	viewRecorder := views.NewRecorder(db, views.Options{FlushInterval: cfg.ViewFlushInterval})
+	viewRecorder.Start()
+
+	postHandler := handlers.NewPostHandler(db, cfg, preview.NewFetcher(preview.Options{
+		AllowPrivate: cfg.LinkPreviewAllowPrivate,
+	}), viewRecorder, eventBus)
+	// RAWG responses are cached in the database to save API quota
+	rawgClient := rawg.New(rawg.Options{
+		BaseURL: cfg.RAWGBaseURL,
+		APIKey:  cfg.RAWGAPIKey,
+		Timeout: cfg.RAWGTimeout,
+	})
+	rawgCache := rawg.NewCachedClient(db, rawgClient, rawg.CacheOptions{
+		SearchTTL: cfg.RAWGSearchCacheTTL,
+		GameTTL:   cfg.RAWGGameCacheTTL,
+		StaleFor:  cfg.RAWGCacheStaleFor,
+	})
+	// Imported games are refreshed straight from RAWG, bypassing the cache
+	gameSyncer := gamesync.New(db, rawgClient, gamesync.Options{
+		Interval:  cfg.RAWGSyncInterval,
+		BatchSize: cfg.RAWGSyncBatchSize,
+		MaxAge:    cfg.RAWGSyncMaxAge,
+	})
+	if cfg.RAWGSyncInterval > 0 {
+		gameSyncer.Start()
+	}
+	gameHandler := handlers.NewGameHandler(db, cfg, rawgCache, gameSyncer)
 	dashboardHandler := handlers.NewDashboardHandler(db)
-	commentHandler := handlers.NewCommentHandler(db)
+	commentHandler := handlers.NewCommentHandler(db, cfg, eventBus)
+	moderationHandler := handlers.NewModerationHandler(db)
+	savedHandler := handlers.NewSavedHandler(db)
+	trendingHandler := handlers.NewTrendingHandler(db)
+	reportHandler := handlers.NewReportHandler(db)
+	reactionHandler := handlers.NewReactionHandler(db, cfg)
+	subscriptionHandler := handlers.NewSubscriptionHandler(db)
+	voteHandler := handlers.NewVoteHandler(db)
+	rawgCacheHandler := handlers.NewRAWGCacheHandler(rawgCache)
 
 	// API routes
 	api := router.Group("/api")
@@ -41,59 +81,126 @@ func Setup(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
-			auth.POST("/appoint-moderator", middleware.AuthMiddleware(), authHandler.AppointModerator)
+			auth.POST("/appoint-moderator", middleware.AuthMiddleware(db), authHandler.AppointModerator)
 		}
 
 		// Dashboard routes (protected)
-		api.GET("/dashboard", middleware.AuthMiddleware(), dashboardHandler.GetDashboard)
+		api.GET("/dashboard", middleware.AuthMiddleware(db), dashboardHandler.GetDashboard)
 
 		// Posts routes
 		posts := api.Group("/posts")
 		{
-			posts.GET("", postHandler.GetPosts)
-			posts.GET("/:id", postHandler.GetPost)
-			posts.POST("", middleware.AuthMiddleware(), postHandler.CreatePost)
-			posts.PUT("/:id", middleware.AuthMiddleware(), postHandler.UpdatePost)
-			posts.DELETE("/:id", middleware.AuthMiddleware(), postHandler.DeletePost)
-			posts.GET("/search", postHandler.SearchPosts)
-			posts.GET("/game/:game_id", postHandler.GetPostsByGame)
-			posts.GET("/user/:user_id", postHandler.GetUserPosts)
+			posts.GET("", middleware.OptionalAuthMiddleware(), postHandler.GetPosts)
+			posts.GET("/:id", middleware.OptionalAuthMiddleware(), postHandler.GetPost)
+			posts.POST("", middleware.AuthMiddleware(db), postHandler.CreatePost)
+			posts.POST("/check-duplicates", middleware.AuthMiddleware(db), postHandler.CheckDuplicates)
+			posts.PUT("/:id", middleware.AuthMiddleware(db), postHandler.UpdatePost)
+			posts.DELETE("/:id", middleware.AuthMiddleware(db), postHandler.DeletePost)
+			posts.GET("/search", middleware.OptionalAuthMiddleware(), postHandler.SearchPosts)
+			posts.GET("/trending", trendingHandler.GetTrendingPosts)
+			posts.GET("/game/:game_id", middleware.OptionalAuthMiddleware(), postHandler.GetPostsByGame)
+			posts.GET("/user/:user_id", middleware.OptionalAuthMiddleware(), postHandler.GetUserPosts)
+			posts.POST("/:id/save", middleware.AuthMiddleware(db), savedHandler.SavePost)
+			posts.DELETE("/:id/save", middleware.AuthMiddleware(db), savedHandler.UnsavePost)
+			posts.POST("/:id/reactions", middleware.AuthMiddleware(db), reactionHandler.ReactToPost)
+			posts.POST("/:id/subscribe", middleware.AuthMiddleware(db), subscriptionHandler.Subscribe)
+			posts.DELETE("/:id/subscribe", middleware.AuthMiddleware(db), subscriptionHandler.Unsubscribe)
+
+			// Moderator-only thread management
+			posts.POST("/:id/pin", middleware.AuthMiddleware(db), moderationHandler.PinPost)
+			posts.DELETE("/:id/pin", middleware.AuthMiddleware(db), moderationHandler.UnpinPost)
+			posts.POST("/:id/lock", middleware.AuthMiddleware(db), moderationHandler.LockPost)
+			posts.DELETE("/:id/lock", middleware.AuthMiddleware(db), moderationHandler.UnlockPost)
+			posts.PUT("/:id/slow-mode", middleware.AuthMiddleware(db), moderationHandler.SetSlowMode)
 		}
 
 		// Comments routes
 		comments := api.Group("/comments")
 		{
-			comments.GET("/post/:post_id", commentHandler.GetCommentsByPost)
+			comments.GET("/post/:post_id", middleware.OptionalAuthMiddleware(), commentHandler.GetCommentsByPost)
 			comments.GET("/post/:post_id/count", commentHandler.GetCommentCount)
-			comments.GET("/:id", commentHandler.GetCommentThread)
-			comments.POST("", middleware.AuthMiddleware(), commentHandler.CreateComment)
-			comments.PUT("/:id", middleware.AuthMiddleware(), commentHandler.UpdateComment)
-			comments.DELETE("/:id", middleware.AuthMiddleware(), commentHandler.DeleteComment)
+			comments.GET("/:id", middleware.OptionalAuthMiddleware(), commentHandler.GetCommentThread)
+			comments.GET("/:id/replies", middleware.OptionalAuthMiddleware(), commentHandler.GetCommentReplies)
+			comments.GET("/:id/context", middleware.OptionalAuthMiddleware(), commentHandler.GetCommentContext)
+			comments.POST("", middleware.AuthMiddleware(db), commentHandler.CreateComment)
+			comments.PUT("/:id", middleware.AuthMiddleware(db), commentHandler.UpdateComment)
+			comments.DELETE("/:id", middleware.AuthMiddleware(db), commentHandler.DeleteComment)
 			comments.GET("/recent", commentHandler.GetRecentComments)
+			comments.POST("/:id/save", middleware.AuthMiddleware(db), savedHandler.SaveComment)
+			comments.DELETE("/:id/save", middleware.AuthMiddleware(db), savedHandler.UnsaveComment)
+			comments.POST("/:id/reactions", middleware.AuthMiddleware(db), reactionHandler.ReactToComment)
+			comments.PUT("/:id/vote", middleware.AuthMiddleware(db), voteHandler.VoteComment)
+			comments.DELETE("/:id/vote", middleware.AuthMiddleware(db), voteHandler.UnvoteComment)
+			comments.POST("/:id/accept", middleware.AuthMiddleware(db), commentHandler.AcceptAnswer)
+			comments.DELETE("/:id/accept", middleware.AuthMiddleware(db), commentHandler.UnacceptAnswer)
+		}
+
+		// Current user routes (protected)
+		me := api.Group("/users/me", middleware.AuthMiddleware(db))
+		{
+			me.GET("/saved", savedHandler.GetSavedItems)
+			me.GET("/saved/folders", savedHandler.GetSavedFolders)
+			me.GET("/subscriptions", subscriptionHandler.GetSubscriptions)
+		}
+
+		// Moderation routes
+		moderation := api.Group("/moderation", middleware.AuthMiddleware(db))
+		{
+			moderation.GET("/actions", moderationHandler.GetModerationActions)
+			moderation.GET("/reports", reportHandler.GetReportQueue)
+			moderation.POST("/reports/:target_type/:target_id/resolve", reportHandler.ResolveReports)
+			moderation.POST("/reports/:target_type/:target_id/dismiss", reportHandler.DismissReports)
+			moderation.GET("/rawg-cache", rawgCacheHandler.GetStats)
+			moderation.DELETE("/rawg-cache", rawgCacheHandler.Purge)
 		}
 
+		// Report routes (protected)
+		reports := api.Group("/reports", middleware.AuthMiddleware(db))
+		{
+			reports.POST("", reportHandler.CreateReport)
+			reports.GET("/mine", reportHandler.GetMyReports)
+		}
+
+		// Available reactions, optionally including a game's custom ones
+		api.GET("/reactions", reactionHandler.GetReactionSet)
+
 		// Games routes - RAWG API
 		games := api.Group("/games")
 		{
 			// RAWG API routes (search-first, no initial load)
 			games.GET("/rawg/search", gameHandler.SearchRAWGGames)
 			games.GET("/rawg/:id", gameHandler.GetRAWGGameDetails)
-			games.POST("/rawg/import", middleware.AuthMiddleware(), gameHandler.ImportFromRAWG)
+			games.POST("/rawg/import", middleware.AuthMiddleware(db), gameHandler.ImportFromRAWG)
+			games.POST("/:id/sync", middleware.AuthMiddleware(db), gameHandler.ResyncGame)
+			games.PUT("/:id/platforms", middleware.AuthMiddleware(db), gameHandler.SetGamePlatforms)
 
 			// Local games routes
 			games.GET("", gameHandler.GetLocalGames)
-			games.POST("", middleware.AuthMiddleware(), gameHandler.CreateLocalGame)
+			games.POST("", middleware.AuthMiddleware(db), gameHandler.CreateLocalGame)
+			games.GET("/:id", middleware.OptionalAuthMiddleware(), gameHandler.GetLocalGameByID)
+			games.GET("/slug/:slug", middleware.OptionalAuthMiddleware(), gameHandler.GetLocalGameBySlug)
 			games.GET("/tag/:tag_slug", gameHandler.GetGamesByTag)
+			games.GET("/trending", trendingHandler.GetTrendingGames)
+
+			// Custom per-game reactions (moderators only)
+			games.POST("/:id/reactions", middleware.AuthMiddleware(db), reactionHandler.CreateCustomReaction)
+			games.DELETE("/:id/reactions/:reaction_id", middleware.AuthMiddleware(db), reactionHandler.DeleteCustomReaction)
 
 			// Tags routes
 			games.GET("/tags", gameHandler.GetAllTags)
+			games.GET("/platforms", gameHandler.GetAllPlatforms)
 		}
 	}
 
-
 	router.NoRoute(func(c *gin.Context) {
 		c.File("./public/index.html")
 	})
 
-	return router
+	stop := func() {
+		if cfg.RAWGSyncInterval > 0 {
+			gameSyncer.Stop()
+		}
+		viewRecorder.Stop()
+	}
+	return router, stop
 }
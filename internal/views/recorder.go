@@ -0,0 +1,171 @@
+package views
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"forumapp/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options configures a Recorder
+type Options struct {
+	Window        time.Duration // A viewer counts once per post within this window
+	FlushInterval time.Duration // How often buffered views are written
+	MaxPending    int           // Flush early once this many views are buffered
+}
+
+// Recorder buffers deduplicated post views in memory and writes them in batches
+type Recorder struct {
+	db   *gorm.DB
+	opts Options
+
+	mu      sync.Mutex
+	window  time.Time
+	seen    map[string]struct{}
+	pending []models.PostView
+
+	flushNow chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewRecorder creates a Recorder; call Start to begin background flushing
+func NewRecorder(db *gorm.DB, opts Options) *Recorder {
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 10 * time.Second
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 1000
+	}
+
+	return &Recorder{
+		db:       db,
+		opts:     opts,
+		seen:     make(map[string]struct{}),
+		flushNow: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the background flush loop
+func (r *Recorder) Start() {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.opts.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-r.flushNow:
+			case <-r.stop:
+				r.flush()
+				return
+			}
+			r.flush()
+		}
+	}()
+}
+
+// Stop flushes any buffered views and stops the background loop
+func (r *Recorder) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// Record buffers a view of a post unless the viewer already viewed it this window
+func (r *Recorder) Record(postID uint, viewerKey string) {
+	now := time.Now()
+	window := now.Truncate(r.opts.Window)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !window.Equal(r.window) {
+		r.window = window
+		r.seen = make(map[string]struct{})
+	}
+
+	key := viewerKey + "|" + strconv.FormatUint(uint64(postID), 10)
+	if _, ok := r.seen[key]; ok {
+		return
+	}
+	r.seen[key] = struct{}{}
+
+	r.pending = append(r.pending, models.PostView{
+		PostID:      postID,
+		ViewerKey:   viewerKey,
+		WindowStart: window,
+		ViewedAt:    now,
+	})
+
+	if len(r.pending) >= r.opts.MaxPending {
+		select {
+		case r.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush writes the buffered views and bumps the per-post view counters in one transaction.
+// Views already stored for the same window (e.g. before a restart) are ignored.
+func (r *Recorder) flush() {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Views of posts deleted since they were recorded would violate the foreign key
+		postIDs := make([]uint, len(batch))
+		for i := range batch {
+			postIDs[i] = batch[i].PostID
+		}
+		var live []uint
+		if err := tx.Model(&models.Post{}).Where("id IN ?", postIDs).Pluck("id", &live).Error; err != nil {
+			return err
+		}
+		exists := make(map[uint]bool, len(live))
+		for _, id := range live {
+			exists[id] = true
+		}
+
+		counts := make(map[uint]int)
+		for i := range batch {
+			if !exists[batch[i].PostID] {
+				continue
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch[i])
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				counts[batch[i].PostID]++
+			}
+		}
+
+		for postID, n := range counts {
+			if err := tx.Model(&models.Post{}).Where("id = ?", postID).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", n)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to flush %d post views: %v", len(batch), err)
+	}
+}
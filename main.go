@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"forumapp/internal/config"
 	"forumapp/internal/database"
+	"forumapp/internal/events"
 	"forumapp/internal/middleware"
 	"forumapp/internal/router"
 )
@@ -20,13 +28,43 @@ func main() {
 	// Initialize database
 	db := database.Initialize(cfg)
 
+	// Maintenance commands run against the database and exit
+	if len(os.Args) > 1 && os.Args[1] == "reconcile-counters" {
+		report, err := database.ReconcileCounters(db)
+		if err != nil {
+			log.Fatal("Failed to reconcile counters: ", err)
+		}
+		log.Printf("Reconciled counters: %d post comment counts, %d comment reply counts, %d comment vote tallies fixed",
+			report.PostCommentCounts, report.CommentReplyCounts, report.CommentVoteCounts)
+		return
+	}
+
+	// Domain events such as mentions are dispatched in-process
+	eventBus := events.NewBus()
+
 	// Setup router
-	r := router.Setup(db, cfg)
+	r, stopJobs := router.Setup(db, cfg, eventBus)
 
 	// Start server
-	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", cfg.Port), Handler: r}
+	go func() {
+		log.Printf("Server starting on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server: ", err)
+		}
+	}()
+
+	// Shut down on SIGINT or SIGTERM once in-flight requests finish, then stop the
+	// background jobs so buffered post views are flushed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
 	}
+	stopJobs()
 }
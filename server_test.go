@@ -2,20 +2,50 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"forumapp/internal/config"
 	"forumapp/internal/database"
+	"forumapp/internal/events"
+	"forumapp/internal/gamesync"
+	"forumapp/internal/handlers"
 	"forumapp/internal/middleware"
+	"forumapp/internal/models"
+	"forumapp/internal/preview"
+	"forumapp/internal/rawg"
+	"forumapp/internal/rawg/rawgtest"
 	"forumapp/internal/router"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
-func setupTestRouter() http.Handler {
+func setupTestRouter(t testing.TB) http.Handler {
+	return setupTestRouterWith(t, nil)
+}
+
+// setupTestRouterWith builds the test router after letting the caller adjust the configuration
+func setupTestRouterWith(t testing.TB, configure func(*config.Config)) http.Handler {
+	return setupTestRouterWithEvents(t, configure, events.NewBus())
+}
+
+// setupTestRouterWithEvents builds the test router publishing domain events to bus;
+// its background jobs stop when the test ends
+func setupTestRouterWithEvents(t testing.TB, configure func(*config.Config), bus *events.Bus) http.Handler {
 	// Load test configuration
 	cfg := &config.Config{
 		Port:         "8080",
@@ -23,6 +53,10 @@ func setupTestRouter() http.Handler {
 		JWTSecret:    "test-secret-key",
 		RAWGAPIKey:   "test-api-key",
 		UploadDir:    "./uploads",
+		ModeratorKey: "test-moderator-key",
+	}
+	if configure != nil {
+		configure(cfg)
 	}
 
 	// Initialize JWT secret
@@ -31,12 +65,19 @@ func setupTestRouter() http.Handler {
 	// Initialize database
 	db := database.Initialize(cfg)
 
+	// Every connection to ":memory:" is a separate database, so keep a single one
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+
 	// Setup router
-	return router.Setup(db, cfg)
+	r, stop := router.Setup(db, cfg, bus)
+	t.Cleanup(stop)
+	return r
 }
 
 func TestRegister(t *testing.T) {
-	r := setupTestRouter()
+	r := setupTestRouter(t)
 
 	user := map[string]string{
 		"username": "testuser",
@@ -56,7 +97,7 @@ func TestRegister(t *testing.T) {
 }
 
 func TestLogin(t *testing.T) {
-	r := setupTestRouter()
+	r := setupTestRouter(t)
 
 	// First register a user
 	user := map[string]string{
@@ -90,7 +131,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestGetPosts(t *testing.T) {
-	r := setupTestRouter()
+	r := setupTestRouter(t)
 
 	req, _ := http.NewRequest("GET", "/api/posts", nil)
 
@@ -103,7 +144,7 @@ func TestGetPosts(t *testing.T) {
 }
 
 func TestGetGames(t *testing.T) {
-	r := setupTestRouter()
+	r := setupTestRouter(t)
 
 	req, _ := http.NewRequest("GET", "/api/games", nil)
 
@@ -112,3 +153,2295 @@ func TestGetGames(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+// registerTestUser registers a user and returns its auth token
+func registerTestUser(t testing.TB, r http.Handler, username, moderatorKey string) string {
+	user := map[string]string{
+		"username":      username,
+		"password":      "testpass123",
+		"email":         username + "@example.com",
+		"moderator_key": moderatorKey,
+	}
+	jsonData, _ := json.Marshal(user)
+
+	req, _ := http.NewRequest("POST", "/api/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register %s: status %d: %s", username, w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Token
+}
+
+// createTestPost creates a post through the API and returns its ID
+func createTestPost(t testing.TB, r http.Handler, token string, fields map[string]string) uint {
+	w := postMultipart(r, token, fields)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create post: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var post struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &post)
+	return post.ID
+}
+
+// postMultipart submits a create-post form and returns the raw response
+func postMultipart(r http.Handler, token string, fields map[string]string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/api/posts", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// doJSON performs an authenticated JSON request against the router
+func doJSON(r http.Handler, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	var body *bytes.Buffer
+	if payload != nil {
+		jsonData, _ := json.Marshal(payload)
+		body = bytes.NewBuffer(jsonData)
+	} else {
+		body = &bytes.Buffer{}
+	}
+
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPinAndLockPost(t *testing.T) {
+	r := setupTestRouter(t)
+
+	userToken := registerTestUser(t, r, "pinuser", "")
+	modToken := registerTestUser(t, r, "pinmod", "test-moderator-key")
+
+	rulesID := createTestPost(t, r, userToken, map[string]string{"title": "Rules", "content": "Be nice", "game_name": "Pin Game"})
+	createTestPost(t, r, userToken, map[string]string{"title": "Newer", "content": "Hello", "game_name": "Pin Game"})
+
+	// Regular users cannot pin
+	w := doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/pin", rulesID), userToken, map[string]string{"scope": "global"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/pin", rulesID), modToken, map[string]string{"scope": "global"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "GET", "/api/posts", "", nil)
+	var listing struct {
+		Posts []struct {
+			ID       uint `json:"id"`
+			IsPinned bool `json:"is_pinned"`
+		} `json:"posts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	if assert.Len(t, listing.Posts, 2) {
+		assert.Equal(t, rulesID, listing.Posts[0].ID)
+		assert.True(t, listing.Posts[0].IsPinned)
+	}
+
+	// Locked posts refuse new comments
+	w = doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/lock", rulesID), modToken, map[string]string{"reason": "flame war"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "POST", "/api/comments", userToken, map[string]interface{}{"post_id": rulesID, "content": "me too"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "locked")
+
+	// Both actions are recorded
+	w = doJSON(r, "GET", fmt.Sprintf("/api/moderation/actions?target_type=post&target_id=%d", rulesID), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"action":"pin"`)
+	assert.Contains(t, w.Body.String(), "flame war")
+}
+
+func TestSavePostAndListSaved(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "saver", "")
+	postID := createTestPost(t, r, token, map[string]string{"title": "Boss guide", "content": "Dodge left", "game_name": "Saved Game"})
+
+	w := doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/save", postID), token, map[string]string{"folder": "guides"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Saving again is idempotent
+	w = doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/save", postID), token, map[string]string{"folder": "guides"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), token, nil)
+	assert.Contains(t, w.Body.String(), `"is_saved":true`)
+
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), "", nil)
+	assert.Contains(t, w.Body.String(), `"is_saved":false`)
+
+	w = doJSON(r, "GET", "/api/users/me/saved?folder=guides", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var saved struct {
+		Items []struct {
+			TargetID uint `json:"target_id"`
+			Post     *struct {
+				Title string `json:"title"`
+			} `json:"post"`
+		} `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	if assert.Len(t, saved.Items, 1) && assert.NotNil(t, saved.Items[0].Post) {
+		assert.Equal(t, "Boss guide", saved.Items[0].Post.Title)
+	}
+
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/posts/%d/save", postID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "GET", "/api/users/me/saved", token, nil)
+	assert.Contains(t, w.Body.String(), `"total":0`)
+}
+
+func TestCursorPaginationStableUnderInserts(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "pager", "")
+	modToken := registerTestUser(t, r, "pagermod", "test-moderator-key")
+
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		ids = append(ids, createTestPost(t, r, token, map[string]string{"title": fmt.Sprintf("Post %d", i), "content": "x", "game_name": "Pager Game"}))
+	}
+	doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/pin", ids[0]), modToken, map[string]string{"scope": "global"})
+
+	type page struct {
+		Posts []struct {
+			ID uint `json:"id"`
+		} `json:"posts"`
+		Pagination struct {
+			NextCursor *string `json:"next_cursor"`
+			PrevCursor *string `json:"prev_cursor"`
+		} `json:"pagination"`
+	}
+	fetch := func(path string) page {
+		w := doJSON(r, "GET", path, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var p page
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return p
+	}
+	idsOf := func(p page) []uint {
+		var out []uint
+		for _, post := range p.Posts {
+			out = append(out, post.ID)
+		}
+		return out
+	}
+
+	first := fetch("/api/posts?limit=2")
+	assert.Equal(t, []uint{ids[0], ids[4]}, idsOf(first))
+	assert.Nil(t, first.Pagination.PrevCursor)
+	if !assert.NotNil(t, first.Pagination.NextCursor) {
+		return
+	}
+
+	// A new post arriving must not shift the next page
+	lateID := createTestPost(t, r, token, map[string]string{"title": "Late", "content": "x", "game_name": "Pager Game"})
+
+	second := fetch("/api/posts?limit=2&cursor=" + *first.Pagination.NextCursor)
+	assert.Equal(t, []uint{ids[3], ids[2]}, idsOf(second))
+	if !assert.NotNil(t, second.Pagination.PrevCursor) {
+		return
+	}
+
+	// Paging back shows the rows right before the second page, including the new post
+	back := fetch("/api/posts?limit=2&cursor=" + *second.Pagination.PrevCursor)
+	assert.Equal(t, []uint{lateID, ids[4]}, idsOf(back))
+	assert.NotNil(t, back.Pagination.PrevCursor)
+
+	w := doJSON(r, "GET", "/api/posts?cursor=not-a-cursor", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// createTestComment creates a comment through the API and returns its ID
+func createTestComment(t testing.TB, r http.Handler, token string, postID uint, parentID *uint, content string) uint {
+	w := doJSON(r, "POST", "/api/comments", token, map[string]interface{}{"post_id": postID, "parent_id": parentID, "content": content})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create comment: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var comment struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &comment)
+	return comment.ID
+}
+
+func TestCommentCountersAndReconcile(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "counter", "")
+	postID := createTestPost(t, r, token, map[string]string{"title": "Counted", "content": "x", "game_name": "Counter Game"})
+	parentID := createTestComment(t, r, token, postID, nil, "first")
+	replyID := createTestComment(t, r, token, postID, &parentID, "reply")
+
+	var post models.Post
+	database.GetDB().First(&post, postID)
+	assert.Equal(t, 2, post.CommentCount)
+
+	var parent models.Comment
+	database.GetDB().First(&parent, parentID)
+	assert.Equal(t, 1, parent.ReplyCount)
+
+	// Simulate drift and repair it
+	database.GetDB().Model(&models.Post{}).Where("id = ?", postID).UpdateColumn("comment_count", 42)
+	report, err := database.ReconcileCounters(database.GetDB())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.PostCommentCounts)
+
+	database.GetDB().First(&post, postID)
+	assert.Equal(t, 2, post.CommentCount)
+
+	// Deleting the parent leaves a tombstone that still counts
+	w := doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", parentID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	database.GetDB().First(&post, postID)
+	assert.Equal(t, 2, post.CommentCount)
+
+	// Deleting the last reply purges it and the tombstone above it
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", replyID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	database.GetDB().First(&post, postID)
+	assert.Equal(t, 0, post.CommentCount)
+}
+
+// BenchmarkListPosts reports queries per listing; it stays constant as the page grows
+func BenchmarkListPosts(b *testing.B) {
+	r := setupTestRouter(b)
+
+	token := registerTestUser(b, r, "bencher", "")
+	for i := 0; i < 50; i++ {
+		postID := createTestPost(b, r, token, map[string]string{"title": fmt.Sprintf("Post %d", i), "content": "x", "game_name": "Bench Game"})
+		createTestComment(b, r, token, postID, nil, "comment")
+	}
+
+	var queries int
+	database.GetDB().Callback().Query().After("gorm:query").Register("bench:count_queries", func(*gorm.DB) {
+		queries++
+	})
+
+	for _, limit := range []int{10, 50} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			queries = 0
+			path := fmt.Sprintf("/api/posts?limit=%d", limit)
+			for i := 0; i < b.N; i++ {
+				doJSON(r, "GET", path, "", nil)
+			}
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// createTestGame creates a local game through the API and returns its ID
+func createTestGame(t testing.TB, r http.Handler, token, title, tags string) uint {
+	w := doJSON(r, "POST", "/api/games", token, map[string]string{"title": title, "tags": tags})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create game: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var game struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &game)
+	return game.ID
+}
+
+func TestCrossPostToMultipleGames(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "crossposter", "")
+	primaryID := createTestGame(t, r, token, "Primary Game", "Shooter")
+	otherID := createTestGame(t, r, token, "Other Game", "Crossplay")
+
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":    "Crossplay is live",
+		"content":  "Both games now share lobbies",
+		"game_id":  fmt.Sprint(primaryID),
+		"game_ids": fmt.Sprintf("%d,%d", primaryID, otherID),
+	})
+
+	w := doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), "", nil)
+	var post struct {
+		GameID uint `json:"game_id"`
+		Games  []struct {
+			ID uint `json:"id"`
+		} `json:"games"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &post)
+	assert.Equal(t, primaryID, post.GameID)
+	assert.Len(t, post.Games, 2)
+
+	// The post is found through the secondary game and its tags
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/game/%d", otherID), "", nil)
+	assert.Contains(t, w.Body.String(), "Crossplay is live")
+
+	w = doJSON(r, "GET", "/api/posts/search?tag=crossplay", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Crossplay is live")
+
+	w = doJSON(r, "GET", "/api/posts/search?tag=unknown-tag", "", nil)
+	assert.NotContains(t, w.Body.String(), "Crossplay is live")
+}
+
+// newPreviewServer serves an OpenGraph page, an oEmbed endpoint and an oversized page
+func newPreviewServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Fallback</title>
+			<meta property="og:title" content="Patch 1.2 notes">
+			<meta property="og:description" content="Balance changes">
+			<meta property="og:image" content="/thumb.png">
+			</head><body>ignored</body></html>`)
+	})
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"title":         "Speedrun clip",
+			"provider_name": "LocalTube",
+			"thumbnail_url": "http://example.com/clip.jpg",
+			"html":          `<iframe src="http://example.com/embed/clip"></iframe>`,
+		})
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1000)+`<meta property="og:title" content="Too far"></head></html>`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLinkPreviewFetcher(t *testing.T) {
+	server := newPreviewServer()
+	defer server.Close()
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	fetcher := preview.NewFetcher(preview.Options{
+		AllowPrivate: true,
+		MaxBytes:     4096,
+		Providers:    []preview.Provider{{Name: "LocalTube", Hosts: []string{"localtube.test"}, Endpoint: server.URL + "/oembed"}},
+	})
+
+	p, err := fetcher.Fetch(context.Background(), server.URL+"/article")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Patch 1.2 notes", p.Title)
+		assert.Equal(t, "Balance changes", p.Description)
+		assert.Equal(t, server.URL+"/thumb.png", p.ImageURL)
+		assert.Empty(t, p.EmbedHTML)
+	}
+
+	// Known video hosts get embed HTML from their oEmbed endpoint
+	p, err = fetcher.Fetch(context.Background(), "http://localtube.test/watch?v=1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Speedrun clip", p.Title)
+		assert.Contains(t, p.EmbedHTML, "<iframe")
+	}
+
+	// Bodies are cut off at the size cap
+	p, err = fetcher.Fetch(context.Background(), server.URL+"/huge")
+	if assert.NoError(t, err) {
+		assert.Empty(t, p.Title)
+	}
+
+	// The default fetcher refuses private and loopback addresses
+	_, err = preview.NewFetcher(preview.Options{}).Fetch(context.Background(), server.URL+"/article")
+	assert.ErrorIs(t, err, preview.ErrBlockedAddress)
+	_, err = preview.NewFetcher(preview.Options{}).Fetch(context.Background(), "http://localhost:"+port+"/article")
+	assert.ErrorIs(t, err, preview.ErrBlockedAddress)
+
+	_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+	assert.ErrorIs(t, err, preview.ErrUnsupportedURL)
+}
+
+func TestCreateLinkPost(t *testing.T) {
+	server := newPreviewServer()
+	defer server.Close()
+
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.LinkPreviewAllowPrivate = true
+	})
+
+	token := registerTestUser(t, r, "linker", "")
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":     "New patch",
+		"post_type": "link",
+		"link_url":  server.URL + "/article",
+		"game_name": "Link Game",
+	})
+
+	w := doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), "", nil)
+	assert.Contains(t, w.Body.String(), `"post_type":"link"`)
+	assert.Contains(t, w.Body.String(), "Patch 1.2 notes")
+}
+
+func TestViewsAndTrending(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.ViewFlushInterval = 10 * time.Millisecond
+	})
+
+	token := registerTestUser(t, r, "viewer", "")
+	quietID := createTestPost(t, r, token, map[string]string{"title": "Quiet", "content": "x", "game_name": "Quiet Game"})
+	hotID := createTestPost(t, r, token, map[string]string{"title": "Hot", "content": "x", "game_name": "Hot Game"})
+
+	// Repeated views by the same viewer count once
+	for i := 0; i < 3; i++ {
+		doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", hotID), token, nil)
+		doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", hotID), "", nil)
+	}
+	doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", quietID), "", nil)
+	commentID := createTestComment(t, r, token, hotID, nil, "so hot")
+	doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d/vote", commentID), token, map[string]int{"value": 1})
+
+	assert.Eventually(t, func() bool {
+		var hot, quiet models.Post
+		database.GetDB().First(&hot, hotID)
+		database.GetDB().First(&quiet, quietID)
+		return hot.ViewCount == 2 && quiet.ViewCount == 1
+	}, time.Second, 10*time.Millisecond)
+
+	w := doJSON(r, "GET", "/api/posts/trending", "", nil)
+	var trending struct {
+		Posts []struct {
+			Post struct {
+				ID uint `json:"id"`
+			} `json:"post"`
+			Views    int64   `json:"views"`
+			Comments int64   `json:"comments"`
+			Votes    int64   `json:"votes"`
+			Score    float64 `json:"score"`
+		} `json:"posts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &trending)
+	if assert.Len(t, trending.Posts, 2) {
+		assert.Equal(t, hotID, trending.Posts[0].Post.ID)
+		assert.Equal(t, int64(2), trending.Posts[0].Views)
+		assert.Equal(t, int64(1), trending.Posts[0].Comments)
+		assert.Equal(t, int64(1), trending.Posts[0].Votes)
+		assert.Equal(t, float64(2+3+2), trending.Posts[0].Score)
+	}
+
+	w = doJSON(r, "GET", "/api/games/trending", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Hot Game")
+}
+
+func TestShutdownFlushesViews(t *testing.T) {
+	r := setupTestRouter(t)
+	token := registerTestUser(t, r, "lateviewer", "")
+	postID := createTestPost(t, r, token, map[string]string{"title": "Last words", "content": "x", "game_name": "Shutdown Game"})
+
+	// A router whose recorder would not flush on its own for an hour
+	app, stop := router.Setup(database.GetDB(), &config.Config{
+		UploadDir:         "./uploads",
+		ViewFlushInterval: time.Hour,
+	}, events.NewBus())
+	doJSON(app, "GET", fmt.Sprintf("/api/posts/%d", postID), "", nil)
+	stop()
+
+	var post models.Post
+	database.GetDB().First(&post, postID)
+	assert.Equal(t, 1, post.ViewCount)
+}
+
+func TestSyncIntervalConfig(t *testing.T) {
+	t.Setenv("RAWG_SYNC_INTERVAL", "0")
+	assert.Equal(t, time.Duration(0), config.Load().RAWGSyncInterval)
+
+	t.Setenv("RAWG_SYNC_INTERVAL", "-5m")
+	assert.Equal(t, time.Hour, config.Load().RAWGSyncInterval)
+}
+
+func TestReportsAndModerationQueue(t *testing.T) {
+	r := setupTestRouter(t)
+
+	authorToken := registerTestUser(t, r, "troll", "")
+	reporterA := registerTestUser(t, r, "reporter_a", "")
+	reporterB := registerTestUser(t, r, "reporter_b", "")
+	modToken := registerTestUser(t, r, "queuemod", "test-moderator-key")
+
+	postID := createTestPost(t, r, reporterA, map[string]string{"title": "Nice thread", "content": "x", "game_name": "Report Game"})
+	commentID := createTestComment(t, r, authorToken, postID, nil, "rude comment")
+
+	for _, token := range []string{reporterA, reporterB} {
+		w := doJSON(r, "POST", "/api/reports", token, map[string]interface{}{
+			"target_type": "comment", "target_id": commentID, "category": "harassment", "details": "insults",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	// Duplicate open reports are rejected
+	w := doJSON(r, "POST", "/api/reports", reporterA, map[string]interface{}{
+		"target_type": "comment", "target_id": commentID, "category": "spam",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(r, "GET", "/api/moderation/reports", reporterA, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "GET", "/api/moderation/reports?cursor=bogus", modToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(r, "GET", "/api/moderation/reports", modToken, nil)
+	var queue struct {
+		Groups []struct {
+			TargetType  string         `json:"target_type"`
+			ReportCount int            `json:"report_count"`
+			Categories  map[string]int `json:"categories"`
+		} `json:"groups"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	if assert.Len(t, queue.Groups, 1) {
+		assert.Equal(t, "comment", queue.Groups[0].TargetType)
+		assert.Equal(t, 2, queue.Groups[0].ReportCount)
+		assert.Equal(t, 2, queue.Groups[0].Categories["harassment"])
+	}
+
+	// Resolve and ban the author in one step
+	w = doJSON(r, "POST", fmt.Sprintf("/api/moderation/reports/comment/%d/resolve", commentID), modToken, map[string]string{"action": "ban", "note": "repeat offender"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "GET", "/api/reports/mine", reporterB, nil)
+	assert.Contains(t, w.Body.String(), `"status":"resolved"`)
+	assert.Contains(t, w.Body.String(), `"outcome":"banned"`)
+
+	w = doJSON(r, "POST", "/api/comments", authorToken, map[string]interface{}{"post_id": postID, "content": "again"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Tokens issued before the ban can still read but no longer write anywhere
+	w = doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d", commentID), authorToken, map[string]string{"content": "edited"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	for _, path := range []string{"/api/posts/%d/save", "/api/posts/%d/subscribe"} {
+		w = doJSON(r, "POST", fmt.Sprintf(path, postID), authorToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	w = doJSON(r, "GET", "/api/users/me/saved", authorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Writes are refused, not let through, when the ban cannot be checked
+	db := database.GetDB()
+	db.Callback().Query().Before("gorm:query").Register("test:fail_users", func(tx *gorm.DB) {
+		if tx.Statement.Table == "users" {
+			tx.AddError(errors.New("injected failure"))
+		}
+	})
+	w = doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/save", postID), reporterA, nil)
+	db.Callback().Query().Remove("test:fail_users")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = doJSON(r, "GET", "/api/moderation/reports", modToken, nil)
+	assert.Contains(t, w.Body.String(), `"total":0`)
+
+	// Each group on the page carries only its own reports
+	spamID := createTestPost(t, r, modToken, map[string]string{"title": "Cheap gold", "content": "x", "game_name": "Report Game"})
+	spamCommentID := createTestComment(t, r, modToken, spamID, nil, "Visit my shop")
+	for _, token := range []string{reporterA, reporterB} {
+		doJSON(r, "POST", "/api/reports", token, map[string]interface{}{"target_type": "post", "target_id": spamID, "category": "spam"})
+	}
+	doJSON(r, "POST", "/api/reports", reporterA, map[string]interface{}{"target_type": "comment", "target_id": spamCommentID, "category": "spam"})
+
+	var grouped struct {
+		Groups []struct {
+			TargetID uint `json:"target_id"`
+			Reports  []struct {
+				TargetID uint `json:"target_id"`
+			} `json:"reports"`
+		} `json:"groups"`
+	}
+	w = doJSON(r, "GET", "/api/moderation/reports", modToken, nil)
+	json.Unmarshal(w.Body.Bytes(), &grouped)
+	if assert.Len(t, grouped.Groups, 2) {
+		assert.Equal(t, spamID, grouped.Groups[0].TargetID)
+		assert.Len(t, grouped.Groups[0].Reports, 2)
+		assert.Equal(t, spamCommentID, grouped.Groups[1].TargetID)
+		if assert.Len(t, grouped.Groups[1].Reports, 1) {
+			assert.Equal(t, spamCommentID, grouped.Groups[1].Reports[0].TargetID)
+		}
+	}
+}
+
+func TestDuplicatePostDetection(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.DuplicatePostMode = "block"
+	})
+
+	token := registerTestUser(t, r, "duplicator", "")
+	gameID := createTestGame(t, r, token, "Server Game", "MMO")
+	otherGameID := createTestGame(t, r, token, "Other Server Game", "MMO")
+
+	originalID := createTestPost(t, r, token, map[string]string{
+		"title":   "Is the EU server down?",
+		"content": "Can't log in since the patch.",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	// A reworded thread is reported as a likely duplicate but still created
+	w := postMultipart(r, token, map[string]string{
+		"title":   "EU server down",
+		"content": "Cannot log in after the patch",
+		"game_id": fmt.Sprint(gameID),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		PossibleDuplicates []struct {
+			ID    uint `json:"id"`
+			Exact bool `json:"exact"`
+		} `json:"possible_duplicates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Len(t, created.PossibleDuplicates, 1)
+	assert.Equal(t, originalID, created.PossibleDuplicates[0].ID)
+	assert.False(t, created.PossibleDuplicates[0].Exact)
+
+	// Exact duplicates, ignoring case and punctuation, are blocked
+	w = postMultipart(r, token, map[string]string{
+		"title":   "is the eu server down",
+		"content": "can't log in since the patch!!",
+		"game_id": fmt.Sprint(gameID),
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"exact":true`)
+
+	// The same thread in another game is not a duplicate
+	w = postMultipart(r, token, map[string]string{
+		"title":   "Is the EU server down?",
+		"content": "Can't log in since the patch.",
+		"game_id": fmt.Sprint(otherGameID),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "possible_duplicates")
+
+	// Drafts can be checked before posting
+	w = doJSON(r, "POST", "/api/posts/check-duplicates", token, map[string]interface{}{
+		"game_id": gameID,
+		"title":   "EU servers down?",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, originalID))
+	assert.Contains(t, w.Body.String(), `"blocks_exact_match":true`)
+
+	// An exact match is blocked even when a newer, reworded thread is just as similar
+	queueGameID := createTestGame(t, r, token, "Queue Game", "MMO")
+	for _, title := range []string{"Login queue stuck", "The login queue stuck"} {
+		createTestPost(t, r, token, map[string]string{
+			"title":   title,
+			"content": "Stuck at position 1000",
+			"game_id": fmt.Sprint(queueGameID),
+		})
+	}
+	w = postMultipart(r, token, map[string]string{
+		"title":   "Login queue stuck",
+		"content": "Stuck at position 1000",
+		"game_id": fmt.Sprint(queueGameID),
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestReactions(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.Reactions = []string{"👍", "🔥"}
+		cfg.UploadDir = t.TempDir()
+	})
+
+	modToken := registerTestUser(t, r, "reactmod", "test-moderator-key")
+	aliceToken := registerTestUser(t, r, "reactalice", "")
+	bobToken := registerTestUser(t, r, "reactbob", "")
+	gameID := createTestGame(t, r, aliceToken, "Reaction Game", "Party")
+
+	postID := createTestPost(t, r, aliceToken, map[string]string{
+		"title":   "React to this",
+		"content": "Reactions are live",
+		"game_id": fmt.Sprint(gameID),
+	})
+	commentID := createTestComment(t, r, bobToken, postID, nil, "Nice")
+
+	// Moderators upload a custom reaction for the game
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("shortcode", "gg")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="gg.png"`)
+	header.Set("Content-Type", "image/png")
+	part, _ := writer.CreatePart(header)
+	part.Write([]byte("\x89PNG\r\n\x1a\n"))
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/games/%d/reactions", gameID), body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+modToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doJSON(r, "GET", fmt.Sprintf("/api/reactions?game_id=%d", gameID), "", nil)
+	assert.Contains(t, w.Body.String(), `"shortcode":"gg"`)
+
+	reactPath := fmt.Sprintf("/api/posts/%d/reactions", postID)
+	w = doJSON(r, "POST", reactPath, aliceToken, map[string]string{"emoji": "🔥"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"reacted":true`)
+	doJSON(r, "POST", reactPath, bobToken, map[string]string{"emoji": "🔥"})
+	doJSON(r, "POST", reactPath, bobToken, map[string]string{"emoji": ":gg:"})
+
+	// Unknown reactions are rejected
+	w = doJSON(r, "POST", reactPath, bobToken, map[string]string{"emoji": "🦄"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Toggling again removes the reaction
+	w = doJSON(r, "POST", reactPath, aliceToken, map[string]string{"emoji": "👍"})
+	assert.Contains(t, w.Body.String(), `"reacted":true`)
+	w = doJSON(r, "POST", reactPath, aliceToken, map[string]string{"emoji": "👍"})
+	assert.Contains(t, w.Body.String(), `"reacted":false`)
+
+	type reactions struct {
+		Reactions []struct {
+			Emoji   string `json:"emoji"`
+			Count   int    `json:"count"`
+			Reacted bool   `json:"reacted"`
+		} `json:"reactions"`
+	}
+
+	// Listings carry the counts and flag the viewer's own reactions
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/game/%d", gameID), aliceToken, nil)
+	var listing struct {
+		Posts []reactions `json:"posts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	if assert.Len(t, listing.Posts, 1) && assert.Len(t, listing.Posts[0].Reactions, 2) {
+		fire := listing.Posts[0].Reactions[0]
+		assert.Equal(t, "🔥", fire.Emoji)
+		assert.Equal(t, 2, fire.Count)
+		assert.True(t, fire.Reacted)
+		gg := listing.Posts[0].Reactions[1]
+		assert.Equal(t, ":gg:", gg.Emoji)
+		assert.False(t, gg.Reacted)
+	}
+
+	// Comment reactions are embedded in the comment tree
+	doJSON(r, "POST", fmt.Sprintf("/api/comments/%d/reactions", commentID), aliceToken, map[string]string{"emoji": "👍"})
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d", postID), aliceToken, nil)
+	var thread struct {
+		Comments []reactions `json:"comments"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &thread)
+	comments := thread.Comments
+	if assert.Len(t, comments, 1) && assert.Len(t, comments[0].Reactions, 1) {
+		assert.Equal(t, 1, comments[0].Reactions[0].Count)
+		assert.True(t, comments[0].Reactions[0].Reacted)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	bus := events.NewBus()
+	var mu sync.Mutex
+	var mentioned []events.UserMentioned
+	bus.Subscribe(events.UserMentionedEvent, func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		mentioned = append(mentioned, e.(events.UserMentioned))
+	})
+	r := setupTestRouterWithEvents(t, nil, bus)
+
+	authorToken := registerTestUser(t, r, "mentioner", "")
+	registerTestUser(t, r, "Zoë_player", "")
+	registerTestUser(t, r, "bob.smith", "")
+	gameID := createTestGame(t, r, authorToken, "Mention Game", "Co-op")
+
+	// Mentions resolve case-insensitively; e-mails, unknown users and self-mentions are not events
+	postID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Squad up",
+		"content": "Hey @zoë_player and @bob.smith. Mail me@example.com, not @nobody or @mentioner",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	w := doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), "", nil)
+	var post struct {
+		Mentions []struct {
+			UserID   uint   `json:"user_id"`
+			Username string `json:"username"`
+			Start    int    `json:"start"`
+			End      int    `json:"end"`
+		} `json:"mentions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &post)
+	if assert.Len(t, post.Mentions, 3) {
+		assert.Equal(t, "Zoë_player", post.Mentions[0].Username)
+		assert.Equal(t, 4, post.Mentions[0].Start)
+		assert.Equal(t, 15, post.Mentions[0].End) // Offsets count characters, not bytes
+		assert.Equal(t, "bob.smith", post.Mentions[1].Username)
+		assert.Equal(t, "mentioner", post.Mentions[2].Username)
+	}
+	assert.Len(t, mentioned, 2)
+
+	// Mentions in comments are published too
+	commentID := createTestComment(t, r, authorToken, postID, nil, "ping @bob.smith")
+	assert.Len(t, mentioned, 3)
+	assert.Equal(t, "comment", mentioned[2].TargetType)
+	assert.Equal(t, commentID, mentioned[2].TargetID)
+	assert.Equal(t, postID, mentioned[2].PostID)
+
+	// Editing keeps the mention but does not publish it again
+	w = doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d", commentID), authorToken, map[string]string{"content": "ping @bob.smith again"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"bob.smith"`)
+	assert.Len(t, mentioned, 3)
+}
+
+func TestThreadSubscriptions(t *testing.T) {
+	r := setupTestRouter(t)
+
+	authorToken := registerTestUser(t, r, "threadauthor", "")
+	readerToken := registerTestUser(t, r, "threadreader", "")
+	gameID := createTestGame(t, r, authorToken, "Subscription Game", "Strategy")
+
+	postID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Patch notes discussion",
+		"content": "What do you think?",
+		"game_id": fmt.Sprint(gameID),
+	})
+	quietID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Quiet thread",
+		"content": "Nobody replies here",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	createTestComment(t, r, readerToken, postID, nil, "Love it")
+	createTestComment(t, r, readerToken, postID, nil, "Actually, not sure")
+	createTestComment(t, r, authorToken, postID, nil, "Thanks!")
+
+	type subscriptionList struct {
+		Subscriptions []struct {
+			PostID      uint  `json:"post_id"`
+			NewComments int64 `json:"new_comments"`
+			Post        struct {
+				Title string `json:"title"`
+			} `json:"post"`
+		} `json:"subscriptions"`
+	}
+
+	// Authors are subscribed automatically; their own comments are not new to them
+	w := doJSON(r, "GET", "/api/users/me/subscriptions?unread=true", authorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list subscriptionList
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if assert.Len(t, list.Subscriptions, 1) {
+		assert.Equal(t, postID, list.Subscriptions[0].PostID)
+		assert.Equal(t, int64(2), list.Subscriptions[0].NewComments)
+		assert.Equal(t, "Patch notes discussion", list.Subscriptions[0].Post.Title)
+	}
+
+	// Visiting the thread returns the previous visit and marks it read
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/%d", postID), authorToken, nil)
+	var post struct {
+		IsSubscribed  bool       `json:"is_subscribed"`
+		LastVisitedAt *time.Time `json:"last_visited_at"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &post)
+	assert.True(t, post.IsSubscribed)
+	assert.NotNil(t, post.LastVisitedAt)
+
+	w = doJSON(r, "GET", "/api/users/me/subscriptions", authorToken, nil)
+	list = subscriptionList{}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Subscriptions, 2)
+	for _, s := range list.Subscriptions {
+		assert.Equal(t, int64(0), s.NewComments, "post %d", s.PostID)
+	}
+
+	// Readers subscribe explicitly, and can unsubscribe again
+	w = doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/subscribe", quietID), readerToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/game/%d", gameID), readerToken, nil)
+	assert.Contains(t, w.Body.String(), `"is_subscribed":true`)
+
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/posts/%d/subscribe", quietID), readerToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(r, "GET", "/api/users/me/subscriptions", readerToken, nil)
+	list = subscriptionList{}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Empty(t, list.Subscriptions)
+}
+
+func TestDeepCommentTrees(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "deepcommenter", "")
+	gameID := createTestGame(t, r, token, "Deep Game", "Puzzle")
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":   "Long argument",
+		"content": "Let's discuss",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	// A chain of eight nested replies
+	ids := []uint{createTestComment(t, r, token, postID, nil, "level 0")}
+	for level := 1; level < 8; level++ {
+		parent := ids[len(ids)-1]
+		ids = append(ids, createTestComment(t, r, token, postID, &parent, fmt.Sprintf("level %d", level)))
+	}
+
+	type node struct {
+		ID                uint   `json:"id"`
+		Depth             int    `json:"depth"`
+		Content           string `json:"content"`
+		HasMoreReplies    bool   `json:"has_more_replies"`
+		ContinueThreadURL string `json:"continue_thread_url"`
+		Replies           []node `json:"replies"`
+	}
+	leaf := func(n node) node {
+		for len(n.Replies) > 0 {
+			n = n.Replies[0]
+		}
+		return n
+	}
+
+	type page struct {
+		Comments []node `json:"comments"`
+	}
+
+	// Deep levels are no longer dropped
+	w := doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d?depth=20", postID), "", nil)
+	var listing page
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	roots := listing.Comments
+	if assert.Len(t, roots, 1) {
+		deepest := leaf(roots[0])
+		assert.Equal(t, "level 7", deepest.Content)
+		assert.Equal(t, 7, deepest.Depth)
+		assert.False(t, deepest.HasMoreReplies)
+	}
+
+	// A depth cap leaves a continuation marker that GetCommentThread picks up
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d?depth=3", postID), "", nil)
+	listing = page{}
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	roots = listing.Comments
+	if assert.Len(t, roots, 1) {
+		cut := leaf(roots[0])
+		assert.Equal(t, ids[3], cut.ID)
+		assert.True(t, cut.HasMoreReplies)
+		assert.Equal(t, fmt.Sprintf("/api/comments/%d?sort=new", ids[3]), cut.ContinueThreadURL)
+
+		w = doJSON(r, "GET", cut.ContinueThreadURL+"&depth=3", "", nil)
+		var thread node
+		json.Unmarshal(w.Body.Bytes(), &thread)
+		assert.Equal(t, ids[3], thread.ID)
+		cut = leaf(thread)
+		assert.Equal(t, ids[6], cut.ID)
+		assert.True(t, cut.HasMoreReplies)
+	}
+
+	w = doJSON(r, "GET", "/api/comments/999999", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCommentSortingAndLoadMore(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "sortcommenter", "")
+	gameID := createTestGame(t, r, token, "Sorting Game", "RPG")
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":   "Best build?",
+		"content": "Share yours",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	// Four top-level comments with vote tallies (up, down)
+	tallies := [][2]int{{2, 0}, {10, 2}, {6, 5}, {0, 3}}
+	var ids []uint
+	for i, tally := range tallies {
+		id := createTestComment(t, r, token, postID, nil, fmt.Sprintf("comment %d", i))
+		database.GetDB().Model(&models.Comment{}).Where("id = ?", id).
+			UpdateColumns(map[string]interface{}{
+				"upvotes": tally[0], "downvotes": tally[1], "score": tally[0] - tally[1],
+				"best_score": models.WilsonScore(tally[0], tally[1]),
+			})
+		ids = append(ids, id)
+	}
+
+	type node struct {
+		ID             uint   `json:"id"`
+		HasMoreReplies bool   `json:"has_more_replies"`
+		MoreRepliesURL string `json:"more_replies_url"`
+		Replies        []node `json:"replies"`
+	}
+	type listing struct {
+		Comments   []node `json:"comments"`
+		Replies    []node `json:"replies"`
+		Pagination struct {
+			Total      int64   `json:"total"`
+			NextCursor *string `json:"next_cursor"`
+		} `json:"pagination"`
+	}
+	fetch := func(path string) listing {
+		w := doJSON(r, "GET", path, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, w.Code, w.Body.String())
+		}
+		var l listing
+		json.Unmarshal(w.Body.Bytes(), &l)
+		return l
+	}
+	commentIDs := func(nodes []node) []uint {
+		var out []uint
+		for _, n := range nodes {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+
+	base := fmt.Sprintf("/api/comments/post/%d", postID)
+	assert.Equal(t, []uint{ids[3], ids[2], ids[1], ids[0]}, commentIDs(fetch(base).Comments))
+	assert.Equal(t, []uint{ids[0], ids[1], ids[2], ids[3]}, commentIDs(fetch(base+"?sort=old").Comments))
+	assert.Equal(t, []uint{ids[1], ids[0], ids[2], ids[3]}, commentIDs(fetch(base+"?sort=top").Comments))
+	assert.Equal(t, ids[2], fetch(base + "?sort=controversial").Comments[0].ID)
+
+	w := doJSON(r, "GET", base+"?sort=hot", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Cursor pagination follows the sort
+	first := fetch(base + "?sort=top&limit=2")
+	assert.Equal(t, []uint{ids[1], ids[0]}, commentIDs(first.Comments))
+	assert.Equal(t, int64(4), first.Pagination.Total)
+	if assert.NotNil(t, first.Pagination.NextCursor) {
+		second := fetch(base + "?sort=top&limit=2&cursor=" + *first.Pagination.NextCursor)
+		assert.Equal(t, []uint{ids[2], ids[3]}, commentIDs(second.Comments))
+		assert.Nil(t, second.Pagination.NextCursor)
+	}
+
+	// Replies beyond the per-comment limit are loaded from the subtree endpoint
+	var replyIDs []uint
+	for i := 0; i < 5; i++ {
+		replyIDs = append(replyIDs, createTestComment(t, r, token, postID, &ids[0], fmt.Sprintf("reply %d", i)))
+	}
+	tree := fetch(base + "?sort=old&replies=2&limit=1")
+	root := tree.Comments[0]
+	assert.Equal(t, []uint{replyIDs[0], replyIDs[1]}, commentIDs(root.Replies))
+	assert.True(t, root.HasMoreReplies)
+
+	more := fetch(root.MoreRepliesURL + "&limit=2")
+	assert.Equal(t, []uint{replyIDs[2], replyIDs[3]}, commentIDs(more.Replies))
+	if assert.NotNil(t, more.Pagination.NextCursor) {
+		rest := fetch(fmt.Sprintf("/api/comments/%d/replies?sort=old&cursor=%s", ids[0], *more.Pagination.NextCursor))
+		assert.Equal(t, []uint{replyIDs[4]}, commentIDs(rest.Replies))
+	}
+}
+
+func TestCommentTombstones(t *testing.T) {
+	r := setupTestRouter(t)
+
+	authorToken := registerTestUser(t, r, "tombauthor", "")
+	replierToken := registerTestUser(t, r, "tombreplier", "")
+	modToken := registerTestUser(t, r, "tombmod", "test-moderator-key")
+	gameID := createTestGame(t, r, authorToken, "Tombstone Game", "Survival")
+	postID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Base building tips",
+		"content": "Post yours",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	parentID := createTestComment(t, r, authorToken, postID, nil, "Walls first")
+	replyID := createTestComment(t, r, replierToken, postID, &parentID, "Agreed")
+	leafID := createTestComment(t, r, replierToken, postID, nil, "Spam link")
+	flameID := createTestComment(t, r, replierToken, postID, nil, "Flame bait")
+	createTestComment(t, r, authorToken, postID, &flameID, "Calm down")
+
+	// The author deleting a comment with replies leaves a tombstone and keeps the tree
+	w := doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", parentID), authorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tombstoned":true`)
+
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/%d", parentID), "", nil)
+	var thread struct {
+		Content   string `json:"content"`
+		IsDeleted bool   `json:"is_deleted"`
+		Replies   []struct {
+			ID uint `json:"id"`
+		} `json:"replies"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &thread)
+	assert.Equal(t, "[deleted]", thread.Content)
+	assert.True(t, thread.IsDeleted)
+	if assert.Len(t, thread.Replies, 1) {
+		assert.Equal(t, replyID, thread.Replies[0].ID)
+	}
+
+	// Tombstones cannot be edited or replied to
+	w = doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d", parentID), authorToken, map[string]string{"content": "back"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(r, "POST", "/api/comments", replierToken, map[string]interface{}{"post_id": postID, "parent_id": parentID, "content": "hi"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Leaf comments are still purged
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", leafID), modToken, map[string]string{"reason": "spam"})
+	assert.Contains(t, w.Body.String(), `"tombstoned":false`)
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/%d", leafID), "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Moderator removals record who removed the comment and why
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", flameID), modToken, map[string]string{"reason": "flame bait"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var flame models.Comment
+	database.GetDB().First(&flame, flameID)
+	assert.Equal(t, "[removed by moderator]", flame.Content)
+	assert.Equal(t, "flame bait", flame.RemovalReason)
+	assert.NotNil(t, flame.RemovedAt)
+	if assert.NotNil(t, flame.RemovedByID) {
+		var mod models.User
+		database.GetDB().Where("username = ?", "tombmod").First(&mod)
+		assert.Equal(t, mod.ID, *flame.RemovedByID)
+	}
+
+	w = doJSON(r, "GET", fmt.Sprintf("/api/moderation/actions?target_type=comment&target_id=%d", flameID), modToken, nil)
+	assert.Contains(t, w.Body.String(), "flame bait")
+}
+
+func TestCommentVotes(t *testing.T) {
+	r := setupTestRouter(t)
+
+	authorToken := registerTestUser(t, r, "voteauthor", "")
+	gameID := createTestGame(t, r, authorToken, "Voting Game", "Strategy")
+	postID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Opening strategies",
+		"content": "What works?",
+		"game_id": fmt.Sprint(gameID),
+	})
+	liked := createTestComment(t, r, authorToken, postID, nil, "Rush the centre")
+	lucky := createTestComment(t, r, authorToken, postID, nil, "Turtle up")
+
+	var voters []string
+	for i := 0; i < 6; i++ {
+		voters = append(voters, registerTestUser(t, r, fmt.Sprintf("voter%d", i), ""))
+	}
+	votePath := func(id uint) string { return fmt.Sprintf("/api/comments/%d/vote", id) }
+
+	// Concurrent and repeated votes count once per user
+	var wg sync.WaitGroup
+	for i, token := range voters {
+		value := 1
+		if i == 0 {
+			value = -1
+		}
+		for repeat := 0; repeat < 2; repeat++ {
+			wg.Add(1)
+			go func(token string, value int) {
+				defer wg.Done()
+				doJSON(r, "PUT", votePath(liked), token, map[string]int{"value": value})
+			}(token, value)
+		}
+	}
+	wg.Wait()
+
+	var comment models.Comment
+	database.GetDB().First(&comment, liked)
+	assert.Equal(t, 5, comment.Upvotes)
+	assert.Equal(t, 1, comment.Downvotes)
+	assert.Equal(t, 4, comment.Score)
+
+	// Changing and clearing a vote updates the tallies
+	w := doJSON(r, "PUT", votePath(liked), voters[0], map[string]int{"value": 1})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		MyVote    int `json:"my_vote"`
+		Upvotes   int `json:"upvotes"`
+		Downvotes int `json:"downvotes"`
+		Score     int `json:"score"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 1, resp.MyVote)
+	assert.Equal(t, 6, resp.Upvotes)
+	assert.Equal(t, 0, resp.Downvotes)
+	assert.Equal(t, 6, resp.Score)
+
+	w = doJSON(r, "DELETE", votePath(liked), voters[1], nil)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 0, resp.MyVote)
+	assert.Equal(t, 5, resp.Score)
+
+	w = doJSON(r, "PUT", votePath(liked), voters[1], map[string]int{"value": 2})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(r, "PUT", votePath(liked), "", map[string]int{"value": 1})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// One upvote out of one ranks below five out of five in the best sort
+	doJSON(r, "PUT", votePath(lucky), voters[1], map[string]int{"value": 1})
+
+	var listing struct {
+		Comments []struct {
+			ID     uint `json:"id"`
+			Score  int  `json:"score"`
+			MyVote int  `json:"my_vote"`
+		} `json:"comments"`
+	}
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d?sort=best", postID), voters[1], nil)
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	if assert.Len(t, listing.Comments, 2) {
+		assert.Equal(t, liked, listing.Comments[0].ID)
+		assert.Equal(t, 5, listing.Comments[0].Score)
+		assert.Equal(t, 0, listing.Comments[0].MyVote)
+		assert.Equal(t, 1, listing.Comments[1].MyVote)
+	}
+
+	// Drifted tallies are repaired by the reconciler
+	database.GetDB().Model(&models.Comment{}).Where("id = ?", liked).UpdateColumn("upvotes", 40)
+	report, err := database.ReconcileCounters(database.GetDB())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.CommentVoteCounts)
+	database.GetDB().First(&comment, liked)
+	assert.Equal(t, 5, comment.Upvotes)
+	assert.Equal(t, models.WilsonScore(5, 0), comment.BestScore)
+}
+
+func TestAcceptedAnswers(t *testing.T) {
+	r := setupTestRouter(t)
+
+	authorToken := registerTestUser(t, r, "asker", "")
+	helperToken := registerTestUser(t, r, "helper", "")
+	modToken := registerTestUser(t, r, "answermod", "test-moderator-key")
+	gameID := createTestGame(t, r, authorToken, "Boss Game", "Action")
+	postID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "How do I beat the dragon?",
+		"content": "Stuck for hours",
+		"game_id": fmt.Sprint(gameID),
+	})
+	otherID := createTestPost(t, r, authorToken, map[string]string{
+		"title":   "Best early weapon?",
+		"content": "Looking for tips",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	first := createTestComment(t, r, helperToken, postID, nil, "Use fire resistance")
+	answer := createTestComment(t, r, helperToken, postID, nil, "Dodge left on the tail swipe")
+	createTestComment(t, r, helperToken, postID, nil, "Good luck")
+	createTestComment(t, r, helperToken, postID, nil, "Same here")
+	acceptPath := func(id uint) string { return fmt.Sprintf("/api/comments/%d/accept", id) }
+
+	// Only the post author or a moderator may accept
+	w := doJSON(r, "POST", acceptPath(answer), helperToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "POST", acceptPath(answer), authorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var listing struct {
+		Comments []struct {
+			ID         uint `json:"id"`
+			IsAccepted bool `json:"is_accepted"`
+		} `json:"comments"`
+		Pagination struct {
+			NextCursor *string `json:"next_cursor"`
+		} `json:"pagination"`
+	}
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d?sort=old&limit=2", postID), "", nil)
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	if assert.Len(t, listing.Comments, 3) {
+		assert.Equal(t, answer, listing.Comments[0].ID)
+		assert.True(t, listing.Comments[0].IsAccepted)
+		assert.Equal(t, first, listing.Comments[1].ID)
+		assert.False(t, listing.Comments[1].IsAccepted)
+	}
+	if assert.NotNil(t, listing.Pagination.NextCursor) {
+		w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d?sort=old&limit=2&cursor=%s", postID, *listing.Pagination.NextCursor), "", nil)
+		json.Unmarshal(w.Body.Bytes(), &listing)
+		assert.Len(t, listing.Comments, 1)
+		assert.NotEqual(t, answer, listing.Comments[0].ID)
+	}
+
+	// Listings include the answer and can be filtered on it
+	var posts struct {
+		Posts []struct {
+			ID             uint `json:"id"`
+			AcceptedAnswer *struct {
+				ID uint `json:"id"`
+			} `json:"accepted_answer"`
+		} `json:"posts"`
+	}
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/game/%d?answered=true", gameID), "", nil)
+	json.Unmarshal(w.Body.Bytes(), &posts)
+	if assert.Len(t, posts.Posts, 1) && assert.NotNil(t, posts.Posts[0].AcceptedAnswer) {
+		assert.Equal(t, answer, posts.Posts[0].AcceptedAnswer.ID)
+	}
+	w = doJSON(r, "GET", fmt.Sprintf("/api/posts/search?game_id=%d&answered=false", gameID), "", nil)
+	json.Unmarshal(w.Body.Bytes(), &posts)
+	if assert.Len(t, posts.Posts, 1) {
+		assert.Equal(t, otherID, posts.Posts[0].ID)
+	}
+	w = doJSON(r, "GET", "/api/posts/search?answered=maybe", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A moderator can switch the answer, and deleting it clears the mark
+	w = doJSON(r, "POST", acceptPath(first), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var post models.Post
+	database.GetDB().First(&post, postID)
+	if assert.NotNil(t, post.AcceptedCommentID) {
+		assert.Equal(t, first, *post.AcceptedCommentID)
+	}
+
+	// A moderator unaccepting on another user's post is audited
+	w = doJSON(r, "DELETE", acceptPath(first), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"post_id":%d,"accepted_comment_id":null}`, postID), w.Body.String())
+	var audit models.ModerationAction
+	database.GetDB().Where("action = ?", "unaccept_answer").First(&audit)
+	assert.Equal(t, first, audit.TargetID)
+	w = doJSON(r, "POST", acceptPath(first), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", first), helperToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	database.GetDB().First(&post, postID)
+	assert.Nil(t, post.AcceptedCommentID)
+
+	// An accepted reply is pinned once; its place in the thread only refers to it
+	root := createTestComment(t, r, helperToken, otherID, nil, "Depends on your class")
+	reply := createTestComment(t, r, helperToken, otherID, &root, "The longsword, for any class")
+	followUp := createTestComment(t, r, authorToken, otherID, &reply, "Thanks!")
+	w = doJSON(r, "POST", acceptPath(reply), authorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	type node struct {
+		ID          uint   `json:"id"`
+		PinnedAbove bool   `json:"pinned_above"`
+		Replies     []node `json:"replies"`
+	}
+	var thread struct {
+		Comments []node `json:"comments"`
+	}
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/post/%d", otherID), "", nil)
+	json.Unmarshal(w.Body.Bytes(), &thread)
+	if assert.Len(t, thread.Comments, 2) {
+		pinned, parent := thread.Comments[0], thread.Comments[1]
+		assert.Equal(t, reply, pinned.ID)
+		assert.False(t, pinned.PinnedAbove)
+		if assert.Len(t, pinned.Replies, 1) {
+			assert.Equal(t, followUp, pinned.Replies[0].ID)
+		}
+		assert.Equal(t, root, parent.ID)
+		if assert.Len(t, parent.Replies, 1) {
+			assert.Equal(t, reply, parent.Replies[0].ID)
+			assert.True(t, parent.Replies[0].PinnedAbove)
+			assert.Empty(t, parent.Replies[0].Replies)
+		}
+	}
+	assert.Equal(t, 1, strings.Count(w.Body.String(), `"content":"Thanks!"`))
+}
+
+// failWrites makes every create, update and delete on table fail until the returned function is called
+func failWrites(db *gorm.DB, table string) func() {
+	fail := func(tx *gorm.DB) {
+		if tx.Statement.Table == table {
+			tx.AddError(errors.New("injected failure"))
+		}
+	}
+	db.Callback().Create().Before("gorm:create").Register("test:fail_create", fail)
+	db.Callback().Update().Before("gorm:update").Register("test:fail_update", fail)
+	db.Callback().Delete().Before("gorm:delete").Register("test:fail_delete", fail)
+
+	return func() {
+		db.Callback().Create().Remove("test:fail_create")
+		db.Callback().Update().Remove("test:fail_update")
+		db.Callback().Delete().Remove("test:fail_delete")
+	}
+}
+
+func TestTransactionalDeletes(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.Reactions = []string{"🔥"}
+	})
+	db := database.GetDB()
+
+	token := registerTestUser(t, r, "txauthor", "")
+	gameID := createTestGame(t, r, token, "Transaction Game", "Puzzle")
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":   "Hardest level?",
+		"content": "Level 9 for me",
+		"game_id": fmt.Sprint(gameID),
+	})
+	parentID := createTestComment(t, r, token, postID, nil, "Level 12")
+	replyID := createTestComment(t, r, token, postID, &parentID, "Agreed")
+	doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d/vote", replyID), token, map[string]int{"value": 1})
+	doJSON(r, "POST", fmt.Sprintf("/api/comments/%d/reactions", replyID), token, map[string]string{"emoji": "🔥"})
+	doJSON(r, "POST", fmt.Sprintf("/api/posts/%d/save", postID), token, nil)
+
+	media, err := os.CreateTemp("uploads", "txdelete-*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	media.Close()
+	defer os.Remove(media.Name())
+	db.Model(&models.Post{}).Where("id = ?", postID).Update("media_url", "/uploads/"+filepath.Base(media.Name()))
+
+	count := func(model interface{}, where string, args ...interface{}) int64 {
+		var n int64
+		db.Model(model).Where(where, args...).Count(&n)
+		return n
+	}
+
+	// A failing comment purge leaves the comment, its extras and the counters alone
+	restore := failWrites(db, "comments")
+	w := doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", replyID), token, nil)
+	restore()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(1), count(&models.Comment{}, "id = ?", replyID))
+	assert.Equal(t, int64(1), count(&models.Reaction{}, "target_type = ? AND target_id = ?", "comment", replyID))
+	assert.Equal(t, int64(1), count(&models.CommentVote{}, "comment_id = ?", replyID))
+	assert.Equal(t, int64(1), count(&models.Comment{}, "id = ? AND reply_count = 1", parentID))
+	assert.Equal(t, int64(1), count(&models.Post{}, "id = ? AND comment_count = 2", postID))
+
+	// A failing post delete keeps everything attached to the post, including its media
+	restore = failWrites(db, "posts")
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/posts/%d", postID), token, nil)
+	restore()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(2), count(&models.Comment{}, "post_id = ?", postID))
+	assert.Equal(t, int64(1), count(&models.Reaction{}, "target_type = ? AND target_id = ?", "comment", replyID))
+	assert.Equal(t, int64(1), count(&models.SavedItem{}, "target_type = ? AND target_id = ?", "post", postID))
+	assert.Equal(t, int64(1), count(&models.Post{}, "id IN (SELECT post_id FROM post_games WHERE game_id = ?)", gameID))
+	assert.FileExists(t, media.Name())
+
+	// Once the delete succeeds, constraints cascade to comments and their votes
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/posts/%d", postID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), count(&models.Comment{}, "post_id = ?", postID))
+	assert.Equal(t, int64(0), count(&models.CommentVote{}, "comment_id = ?", replyID))
+	assert.Equal(t, int64(0), count(&models.Reaction{}, "target_id = ?", replyID))
+	assert.Equal(t, int64(0), count(&models.SavedItem{}, "target_id = ?", postID))
+	assert.NoFileExists(t, media.Name())
+
+	// Tags are not left behind when creating the game fails
+	restore = failWrites(db, "games")
+	w = doJSON(r, "POST", "/api/games", token, map[string]string{"title": "Broken Game", "tags": "Brand New Tag"})
+	restore()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(0), count(&models.Tag{}, "slug = ?", "brand-new-tag"))
+
+	// Resolving reports with a delete keeps the content when the reports cannot be closed
+	modToken := registerTestUser(t, r, "txmod", "test-moderator-key")
+	reportedID := createTestPost(t, r, token, map[string]string{"title": "Spam", "content": "Buy now", "game_id": fmt.Sprint(gameID)})
+	doJSON(r, "POST", "/api/reports", modToken, map[string]interface{}{"target_type": "post", "target_id": reportedID, "category": "spam"})
+	resolvePath := fmt.Sprintf("/api/moderation/reports/post/%d/resolve", reportedID)
+
+	restore = failWrites(db, "reports")
+	w = doJSON(r, "POST", resolvePath, modToken, map[string]string{"action": "delete"})
+	restore()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(1), count(&models.Post{}, "id = ?", reportedID))
+	assert.Equal(t, int64(1), count(&models.Report{}, "target_id = ? AND status = ?", reportedID, "open"))
+	assert.Equal(t, int64(0), count(&models.ModerationAction{}, "action = ? AND target_id = ?", "delete", reportedID))
+
+	w = doJSON(r, "POST", resolvePath, modToken, map[string]string{"action": "delete"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), count(&models.Post{}, "id = ?", reportedID))
+	assert.Equal(t, int64(1), count(&models.Report{}, "target_id = ? AND status = ?", reportedID, "resolved"))
+	assert.Equal(t, int64(1), count(&models.ModerationAction{}, "action = ? AND target_id = ?", "delete", reportedID))
+
+	// A moderator removal is rolled back when its audit entry cannot be written
+	keptID := createTestPost(t, r, token, map[string]string{"title": "Speedruns", "content": "Routes?", "game_id": fmt.Sprint(gameID)})
+	removedID := createTestComment(t, r, token, keptID, nil, "Off topic")
+	restore = failWrites(db, "moderation_actions")
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", removedID), modToken, map[string]string{"reason": "off topic"})
+	restore()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(1), count(&models.Comment{}, "id = ?", removedID))
+
+	w = doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", removedID), modToken, map[string]string{"reason": "off topic"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), count(&models.Comment{}, "id = ?", removedID))
+	assert.Equal(t, int64(1), count(&models.ModerationAction{}, "action = ? AND target_type = ? AND target_id = ?", "delete", "comment", removedID))
+}
+
+func TestCommentThrottling(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.CommentRateLimit = 3
+		cfg.NewAccountCommentRateLimit = 1
+		cfg.CommentRateWindow = time.Hour
+		cfg.NewAccountAge = 24 * time.Hour
+	})
+
+	newToken := registerTestUser(t, r, "freshuser", "")
+	veteranToken := registerTestUser(t, r, "veteran", "")
+	modToken := registerTestUser(t, r, "slowmod", "test-moderator-key")
+	database.GetDB().Model(&models.User{}).Where("username IN ?", []string{"veteran", "slowmod"}).
+		Update("created_at", time.Now().Add(-48*time.Hour))
+
+	gameID := createTestGame(t, r, modToken, "Heated Game", "Shooter")
+	postID := createTestPost(t, r, modToken, map[string]string{
+		"title":   "Patch notes discussion",
+		"content": "Thoughts?",
+		"game_id": fmt.Sprint(gameID),
+	})
+	otherID := createTestPost(t, r, modToken, map[string]string{
+		"title":   "Map rotation",
+		"content": "Favourites?",
+		"game_id": fmt.Sprint(gameID),
+	})
+	comment := func(token string, postID uint) *httptest.ResponseRecorder {
+		return doJSON(r, "POST", "/api/comments", token, map[string]interface{}{"post_id": postID, "content": "hot take"})
+	}
+	var throttled struct {
+		Code       string `json:"code"`
+		RetryAfter int    `json:"retry_after"`
+	}
+
+	// New accounts get the stricter limit
+	assert.Equal(t, http.StatusCreated, comment(newToken, postID).Code)
+	w := comment(newToken, postID)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &throttled)
+	assert.Equal(t, "rate_limited", throttled.Code)
+	assert.InDelta(t, 3600, throttled.RetryAfter, 5)
+	assert.Equal(t, fmt.Sprint(throttled.RetryAfter), w.Header().Get("Retry-After"))
+
+	// Established accounts get the regular limit
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusCreated, comment(veteranToken, otherID).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, comment(veteranToken, otherID).Code)
+
+	// Only moderators can set slow mode, and they are not throttled themselves
+	slowMode := fmt.Sprintf("/api/posts/%d/slow-mode", postID)
+	w = doJSON(r, "PUT", slowMode, veteranToken, map[string]int{"seconds": 60})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "PUT", slowMode, modToken, map[string]int{"seconds": -1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(r, "PUT", slowMode, modToken, map[string]int{"seconds": 60})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"slow_mode_seconds":60`)
+
+	assert.Equal(t, http.StatusCreated, comment(modToken, postID).Code)
+	assert.Equal(t, http.StatusCreated, comment(modToken, postID).Code)
+
+	// Slow mode applies per user within the thread
+	database.GetDB().Model(&models.Comment{}).Where("user_id = (SELECT id FROM users WHERE username = ?)", "veteran").
+		Update("created_at", time.Now().Add(-2*time.Hour))
+	assert.Equal(t, http.StatusCreated, comment(veteranToken, postID).Code)
+	w = comment(veteranToken, postID)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &throttled)
+	assert.Equal(t, "slow_mode", throttled.Code)
+	assert.InDelta(t, 60, throttled.RetryAfter, 2)
+	assert.Equal(t, http.StatusCreated, comment(veteranToken, otherID).Code)
+
+}
+
+func TestConcurrentCommentRateLimit(t *testing.T) {
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.DatabasePath = filepath.Join(t.TempDir(), "forum.db")
+		cfg.CommentRateLimit = 3
+		cfg.CommentRateWindow = time.Hour
+	})
+	// A file database lets requests run on separate connections
+	sqlDB, _ := database.GetDB().DB()
+	sqlDB.SetMaxOpenConns(8)
+
+	token := registerTestUser(t, r, "burster", "")
+	postID := createTestPost(t, r, token, map[string]string{"title": "Burst", "content": "x", "game_name": "Burst Game"})
+
+	// Concurrent comments cannot slip past the limit together
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := doJSON(r, "POST", "/api/comments", token, map[string]interface{}{"post_id": postID, "content": "spam"})
+			if w.Code == http.StatusCreated {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), created.Load())
+	assert.True(t, database.GetDB().Migrator().HasIndex(&models.Comment{}, "idx_comments_user_created"))
+}
+
+func TestCommentPermalinkContext(t *testing.T) {
+	r := setupTestRouter(t)
+
+	token := registerTestUser(t, r, "permalinker", "")
+	gameID := createTestGame(t, r, token, "Context Game", "Adventure")
+	postID := createTestPost(t, r, token, map[string]string{
+		"title":   "Deep discussion",
+		"content": "Reply away",
+		"game_id": fmt.Sprint(gameID),
+	})
+
+	var chain []uint
+	var parent *uint
+	for i := 0; i < 6; i++ {
+		id := createTestComment(t, r, token, postID, parent, fmt.Sprintf("level %d", i))
+		chain = append(chain, id)
+		parent = &chain[len(chain)-1]
+	}
+	doJSON(r, "PUT", fmt.Sprintf("/api/comments/%d/vote", chain[0]), token, map[string]int{"value": 1})
+
+	type node struct {
+		ID                uint   `json:"id"`
+		Content           string `json:"content"`
+		MyVote            int    `json:"my_vote"`
+		HasMoreReplies    bool   `json:"has_more_replies"`
+		ContinueThreadURL string `json:"continue_thread_url"`
+		Replies           []node `json:"replies"`
+	}
+	var permalink struct {
+		Post struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+			User  struct {
+				Username string `json:"username"`
+			} `json:"user"`
+		} `json:"post"`
+		Ancestors []node `json:"ancestors"`
+		Comment   node   `json:"comment"`
+	}
+
+	w := doJSON(r, "GET", fmt.Sprintf("/api/comments/%d/context?depth=1", chain[3]), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &permalink)
+
+	assert.Equal(t, postID, permalink.Post.ID)
+	assert.Equal(t, "Deep discussion", permalink.Post.Title)
+	assert.Equal(t, "permalinker", permalink.Post.User.Username)
+
+	var ancestorIDs []uint
+	for _, a := range permalink.Ancestors {
+		ancestorIDs = append(ancestorIDs, a.ID)
+		assert.Empty(t, a.Replies)
+	}
+	assert.Equal(t, chain[:3], ancestorIDs)
+	assert.Equal(t, 1, permalink.Ancestors[0].MyVote)
+
+	assert.Equal(t, chain[3], permalink.Comment.ID)
+	if assert.Len(t, permalink.Comment.Replies, 1) {
+		reply := permalink.Comment.Replies[0]
+		assert.Equal(t, chain[4], reply.ID)
+		assert.True(t, reply.HasMoreReplies)
+		assert.Contains(t, reply.ContinueThreadURL, fmt.Sprintf("/api/comments/%d", chain[4]))
+	}
+
+	// A root comment has no ancestors
+	w = doJSON(r, "GET", fmt.Sprintf("/api/comments/%d/context", chain[0]), "", nil)
+	json.Unmarshal(w.Body.Bytes(), &permalink)
+	assert.Empty(t, permalink.Ancestors)
+	assert.Contains(t, w.Body.String(), `"ancestors":[]`)
+
+	w = doJSON(r, "GET", "/api/comments/9999/context", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// fakeRAWGGames are the games served by the fake RAWG API in tests
+var fakeRAWGGames = []models.RAWGGame{
+	{ID: 3498, Name: "Grand Theft Auto V", Slug: "grand-theft-auto-v", Rating: 4.47,
+		Genres:    []models.RAWGTag{{ID: 4, Name: "Action", Slug: "action"}},
+		Tags:      []models.RAWGTag{{ID: 31, Name: "Singleplayer", Slug: "singleplayer"}},
+		Platforms: []models.RAWGPlatform{rawgPlatform(4, "PC", "pc"), rawgPlatform(187, "PlayStation 5", "playstation5")}},
+	{ID: 3328, Name: "The Witcher 3: Wild Hunt", Slug: "the-witcher-3-wild-hunt", Rating: 4.65,
+		Platforms: []models.RAWGPlatform{rawgPlatform(4, "PC", "pc"), rawgPlatform(7, "Nintendo Switch", "nintendo-switch")}},
+}
+
+// rawgPlatform builds a platform entry as RAWG nests it
+func rawgPlatform(id int, name, slug string) (p models.RAWGPlatform) {
+	p.Platform.ID, p.Platform.Name, p.Platform.Slug = id, name, slug
+	return p
+}
+
+func TestRAWGClient(t *testing.T) {
+	server := rawgtest.NewServer(fakeRAWGGames...)
+	defer server.Close()
+
+	client := rawg.New(rawg.Options{BaseURL: server.URL, APIKey: "test-api-key", Backoff: time.Millisecond})
+	ctx := context.Background()
+
+	game, err := client.GetGame(ctx, "the-witcher-3-wild-hunt")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3328, game.ID)
+	}
+	results, err := client.SearchGames(ctx, "grand theft", 1, 10)
+	if assert.NoError(t, err) && assert.Len(t, results.Results, 1) {
+		assert.Equal(t, 3498, results.Results[0].ID)
+	}
+
+	// Server errors and rate limiting are retried with backoff
+	before := server.Requests()
+	server.FailNext(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	_, err = client.GetGame(ctx, "3498")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, server.Requests()-before)
+
+	before = server.Requests()
+	server.FailNext(http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+	_, err = client.GetGame(ctx, "3498")
+	assert.ErrorIs(t, err, rawg.ErrRateLimited)
+	assert.Equal(t, 3, server.Requests()-before)
+
+	// Other failures are not retried
+	before = server.Requests()
+	_, err = client.GetGame(ctx, "999999")
+	assert.ErrorIs(t, err, rawg.ErrNotFound)
+	server.FailNext(http.StatusUnauthorized)
+	_, err = client.GetGame(ctx, "3498")
+	assert.ErrorIs(t, err, rawg.ErrUnavailable)
+	var rawgErr *rawg.Error
+	if assert.ErrorAs(t, err, &rawgErr) {
+		assert.Equal(t, http.StatusUnauthorized, rawgErr.StatusCode)
+	}
+	assert.Equal(t, 2, server.Requests()-before)
+
+	// Each attempt is bounded by the timeout
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	_, err = rawg.New(rawg.Options{BaseURL: slow.URL, Timeout: 20 * time.Millisecond, MaxRetries: -1}).GetGame(ctx, "1")
+	assert.ErrorIs(t, err, rawg.ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRAWGHandlers(t *testing.T) {
+	server := rawgtest.NewServer(fakeRAWGGames...)
+	defer server.Close()
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.RAWGBaseURL = server.URL
+	})
+	token := registerTestUser(t, r, "importer", "")
+
+	w := doJSON(r, "GET", "/api/games/rawg/search?q=witcher", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "The Witcher 3: Wild Hunt")
+
+	w = doJSON(r, "GET", "/api/games/rawg/999999", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Importing creates the game with its tags and genres, once
+	w = doJSON(r, "POST", "/api/games/rawg/import", token, map[string]int{"rawg_id": 3498})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"slug":"singleplayer"`)
+	assert.Contains(t, w.Body.String(), `"slug":"action"`)
+
+	before := server.Requests()
+	w = doJSON(r, "POST", "/api/games/rawg/import", token, map[string]int{"rawg_id": 3498})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "game already imported")
+	assert.Equal(t, before, server.Requests())
+
+	// Upstream failures are reported without their details
+	server.FailNext(http.StatusForbidden)
+	w = doJSON(r, "GET", "/api/games/rawg/search?q=grand", "", nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.NotContains(t, w.Body.String(), "deadbeef")
+	assert.NotContains(t, w.Body.String(), "403")
+}
+
+// gatedRAWG counts upstream calls and holds them until release is closed
+type gatedRAWG struct {
+	rawg.Client
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (g *gatedRAWG) SearchGames(ctx context.Context, query string, page, pageSize int) (*models.RAWGSearchResponse, error) {
+	g.calls.Add(1)
+	<-g.release
+	return g.Client.SearchGames(ctx, query, page, pageSize)
+}
+
+func (g *gatedRAWG) GetGame(ctx context.Context, idOrSlug string) (*models.RAWGGame, error) {
+	g.calls.Add(1)
+	<-g.release
+	return g.Client.GetGame(ctx, idOrSlug)
+}
+
+func TestRAWGCache(t *testing.T) {
+	server := rawgtest.NewServer(fakeRAWGGames...)
+	defer server.Close()
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.RAWGBaseURL = server.URL
+	})
+	db := database.GetDB()
+	modToken := registerTestUser(t, r, "cachemod", "test-moderator-key")
+	userToken := registerTestUser(t, r, "cacheuser", "")
+
+	// Repeated searches are served from the cache, whatever the spacing and case
+	before := server.Requests()
+	w := doJSON(r, "GET", "/api/games/rawg/search?q=witcher", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(r, "GET", "/api/games/rawg/search?q=%20Witcher", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "The Witcher 3: Wild Hunt")
+	assert.Equal(t, 1, server.Requests()-before)
+
+	var stats rawg.CacheStats
+	w = doJSON(r, "GET", "/api/moderation/rawg-cache", modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, rawg.CacheStats{Hits: 1, Misses: 1, Entries: 1}, stats)
+	w = doJSON(r, "GET", "/api/moderation/rawg-cache", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Identical concurrent misses share one upstream request
+	upstream := &gatedRAWG{
+		Client:  rawg.New(rawg.Options{BaseURL: server.URL, APIKey: "test-api-key"}),
+		release: make(chan struct{}),
+	}
+	cache := rawg.NewCachedClient(db, upstream, rawg.CacheOptions{GameTTL: time.Millisecond, StaleFor: time.Hour})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	ratings := make([]float64, 5)
+	for i := range ratings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if game, err := cache.GetGame(ctx, "3498"); err == nil {
+				ratings[i] = game.Rating
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool { return upstream.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(upstream.release)
+	wg.Wait()
+	assert.Equal(t, []float64{4.47, 4.47, 4.47, 4.47, 4.47}, ratings)
+	assert.Equal(t, int32(1), upstream.calls.Load())
+
+	// Expired entries are served stale while they are refreshed in the background
+	updated := fakeRAWGGames[0]
+	updated.Rating = 4.9
+	server.AddGame(updated)
+	time.Sleep(5 * time.Millisecond)
+	game, err := cache.GetGame(ctx, "3498")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4.47, game.Rating)
+	}
+	assert.Eventually(t, func() bool {
+		var entry models.RAWGCacheEntry
+		db.Where("key = ?", "game:3498").First(&entry)
+		return strings.Contains(string(entry.Body), "4.9")
+	}, time.Second, 5*time.Millisecond)
+	game, err = cache.GetGame(ctx, "3498")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4.9, game.Rating)
+	}
+	assert.Eventually(t, func() bool { return upstream.calls.Load() == 3 }, time.Second, time.Millisecond)
+
+	// Errors are not cached
+	for range 2 {
+		_, err = cache.GetGame(ctx, "999999")
+		assert.ErrorIs(t, err, rawg.ErrNotFound)
+	}
+	assert.Equal(t, int32(5), upstream.calls.Load())
+	stats, err = cache.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.StaleHits)
+	assert.Equal(t, int64(2), stats.Entries)
+
+	// Moderators can purge by kind or everything
+	w = doJSON(r, "DELETE", "/api/moderation/rawg-cache?kind=bogus", modToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(r, "DELETE", "/api/moderation/rawg-cache?kind=game", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "DELETE", "/api/moderation/rawg-cache?kind=game&expired=true", modToken, nil)
+	assert.JSONEq(t, `{"purged":0}`, w.Body.String())
+	w = doJSON(r, "DELETE", "/api/moderation/rawg-cache?kind=game", modToken, nil)
+	assert.JSONEq(t, `{"purged":1}`, w.Body.String())
+	w = doJSON(r, "DELETE", "/api/moderation/rawg-cache", modToken, nil)
+	assert.JSONEq(t, `{"purged":1}`, w.Body.String())
+
+	// Writes prune entries that can no longer be served
+	pruning := rawg.NewCachedClient(db, upstream, rawg.CacheOptions{
+		GameTTL: time.Millisecond, StaleFor: time.Millisecond, PruneInterval: time.Millisecond,
+	})
+	_, err = pruning.GetGame(ctx, "3498")
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = pruning.GetGame(ctx, "3328")
+	assert.NoError(t, err)
+	var keys []string
+	db.Model(&models.RAWGCacheEntry{}).Pluck("key", &keys)
+	assert.Equal(t, []string{"game:3328"}, keys)
+
+	// Callers waiting on a shared request give up when their own context ends
+	held := &gatedRAWG{Client: upstream.Client, release: make(chan struct{})}
+	waiting := rawg.NewCachedClient(db, held, rawg.CacheOptions{})
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := waiting.GetGame(ctx, "3498")
+		leaderDone <- err
+	}()
+	assert.Eventually(t, func() bool { return held.calls.Load() == 1 }, time.Second, time.Millisecond)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	_, err = waiting.GetGame(timeout, "3498")
+	cancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	close(held.release)
+	assert.NoError(t, <-leaderDone)
+
+	// A request that panics still releases the callers waiting on it
+	crashing := &gatedRAWG{Client: panickingRAWG{}, release: make(chan struct{})}
+	crashy := rawg.NewCachedClient(db, crashing, rawg.CacheOptions{})
+	go func() {
+		defer func() { recover() }()
+		crashy.GetGame(ctx, "42")
+	}()
+	assert.Eventually(t, func() bool { return crashing.calls.Load() == 1 }, time.Second, time.Millisecond)
+	waited := make(chan error, 1)
+	go func() {
+		_, err := crashy.GetGame(ctx, "42")
+		waited <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(crashing.release)
+	select {
+	case err := <-waited:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("caller still waiting after the shared request panicked")
+	}
+}
+
+// panickingRAWG panics on every game lookup, like a client choking on a malformed response
+type panickingRAWG struct {
+	rawg.Client
+}
+
+func (panickingRAWG) GetGame(context.Context, string) (*models.RAWGGame, error) {
+	panic("malformed response")
+}
+
+func TestRAWGGameSync(t *testing.T) {
+	server := rawgtest.NewServer(fakeRAWGGames...)
+	defer server.Close()
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.RAWGBaseURL = server.URL
+	})
+	db := database.GetDB()
+	modToken := registerTestUser(t, r, "syncmod", "test-moderator-key")
+	userToken := registerTestUser(t, r, "syncuser", "")
+
+	var gta, witcher models.Game
+	w := doJSON(r, "POST", "/api/games/rawg/import", userToken, map[string]int{"rawg_id": 3498})
+	json.Unmarshal(w.Body.Bytes(), &gta)
+	assert.NotNil(t, gta.LastSyncedAt)
+	w = doJSON(r, "POST", "/api/games/rawg/import", userToken, map[string]int{"rawg_id": 3328})
+	json.Unmarshal(w.Body.Bytes(), &witcher)
+	localID := createTestGame(t, r, userToken, "Homebrew Quest", "Indie")
+
+	// Moderators can resync a game right away; its metadata and tags follow RAWG
+	updated := fakeRAWGGames[0]
+	updated.Rating, updated.Metacritic = 4.9, 97
+	updated.Tags = []models.RAWGTag{{ID: 36, Name: "Open World", Slug: "open-world"}}
+	server.AddGame(updated)
+
+	w = doJSON(r, "POST", fmt.Sprintf("/api/games/%d/sync", gta.ID), userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "POST", fmt.Sprintf("/api/games/%d/sync", gta.ID), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var synced models.Game
+	json.Unmarshal(w.Body.Bytes(), &synced)
+	assert.Equal(t, 4.9, synced.Rating)
+	assert.Equal(t, 97, synced.Metacritic)
+	assert.Equal(t, gta.Slug, synced.Slug)
+	assert.True(t, synced.LastSyncedAt.After(*gta.LastSyncedAt))
+	assert.Contains(t, w.Body.String(), `"slug":"open-world"`)
+	assert.Contains(t, w.Body.String(), `"slug":"action"`)
+	assert.NotContains(t, w.Body.String(), `"slug":"singleplayer"`)
+
+	w = doJSON(r, "POST", fmt.Sprintf("/api/games/%d/sync", localID), modToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(r, "POST", "/api/games/9999/sync", modToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Batches refresh the least recently synced games first, within the budget
+	syncer := gamesync.New(db, rawg.New(rawg.Options{BaseURL: server.URL, APIKey: "test-api-key", Backoff: time.Millisecond}),
+		gamesync.Options{BatchSize: 1, MaxAge: time.Hour, Pace: time.Millisecond})
+	ctx := context.Background()
+	makeDue := func() {
+		db.Model(&models.Game{}).Where("id = ?", gta.ID).Update("last_synced_at", time.Now().Add(-3*time.Hour))
+		db.Model(&models.Game{}).Where("id = ?", witcher.ID).Update("last_synced_at", time.Now().Add(-2*time.Hour))
+	}
+	loadGame := func(id uint) (game models.Game) {
+		db.First(&game, id)
+		return game
+	}
+	makeDue()
+	updatedWitcher := fakeRAWGGames[1]
+	updatedWitcher.Playtime = 52
+	server.AddGame(updatedWitcher)
+
+	before := server.Requests()
+	n, err := syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 0, loadGame(witcher.ID).Playtime)
+	n, err = syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 52, loadGame(witcher.ID).Playtime)
+	n, err = syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 2, server.Requests()-before)
+
+	// Being rate limited ends the batch early
+	makeDue()
+	syncer = gamesync.New(db, rawg.New(rawg.Options{BaseURL: server.URL, APIKey: "test-api-key", Backoff: time.Millisecond}),
+		gamesync.Options{BatchSize: 2, MaxAge: time.Hour, Pace: time.Millisecond})
+	before = server.Requests()
+	server.FailNext(http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+	n, err = syncer.SyncBatch(ctx)
+	assert.ErrorIs(t, err, rawg.ErrRateLimited)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 3, server.Requests()-before)
+
+	// Games gone from RAWG keep their metadata and move to the back of the queue
+	db.Model(&models.Game{}).Where("id = ?", witcher.ID).Update("rawg_id", 999999)
+	n, err = syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	synced = loadGame(witcher.ID)
+	assert.Equal(t, 52, synced.Playtime)
+	assert.WithinDuration(t, time.Now(), *synced.LastSyncedAt, time.Minute)
+
+	// Games that fail to sync go to the back of the queue instead of blocking the others
+	db.Model(&models.Game{}).Where("id = ?", witcher.ID).Update("rawg_id", 3328)
+	makeDue()
+	syncer = gamesync.New(db, rawg.New(rawg.Options{BaseURL: server.URL, APIKey: "test-api-key", Backoff: time.Millisecond}),
+		gamesync.Options{BatchSize: 1, MaxAge: time.Hour, Pace: time.Millisecond})
+	server.FailNext(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	n, err = syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.WithinDuration(t, time.Now(), *loadGame(gta.ID).LastSyncedAt, time.Minute)
+	n, err = syncer.SyncBatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.WithinDuration(t, time.Now(), *loadGame(witcher.ID).LastSyncedAt, time.Minute)
+}
+
+func TestGamePlatforms(t *testing.T) {
+	server := rawgtest.NewServer(fakeRAWGGames...)
+	defer server.Close()
+	r := setupTestRouterWith(t, func(cfg *config.Config) {
+		cfg.RAWGBaseURL = server.URL
+	})
+	modToken := registerTestUser(t, r, "platformmod", "test-moderator-key")
+	token := registerTestUser(t, r, "platformuser", "")
+
+	// Imported games bring their RAWG platforms; local games name theirs
+	var gta, witcher, local models.Game
+	w := doJSON(r, "POST", "/api/games/rawg/import", token, map[string]int{"rawg_id": 3498})
+	json.Unmarshal(w.Body.Bytes(), &gta)
+	w = doJSON(r, "POST", "/api/games/rawg/import", token, map[string]int{"rawg_id": 3328})
+	json.Unmarshal(w.Body.Bytes(), &witcher)
+	if assert.Len(t, gta.Platforms, 2) {
+		assert.Equal(t, "playstation5", gta.Platforms[1].Slug)
+	}
+
+	w = doJSON(r, "POST", "/api/games", token, map[string]string{"title": "Homebrew Racer", "platforms": "pc, Xbox One, PC"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &local)
+	if assert.Len(t, local.Platforms, 2) {
+		assert.Equal(t, gta.Platforms[0].ID, local.Platforms[0].ID)
+		assert.Equal(t, "xbox-one", local.Platforms[1].Slug)
+	}
+
+	var platforms []models.Platform
+	w = doJSON(r, "GET", "/api/games/platforms", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &platforms)
+	assert.Len(t, platforms, 4)
+
+	gameIDs := func(path string) []uint {
+		var resp struct{ Games []models.Game }
+		w := doJSON(r, "GET", path, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var ids []uint
+		for _, game := range resp.Games {
+			ids = append(ids, game.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []uint{gta.ID}, gameIDs("/api/games?platform=playstation5"))
+	assert.ElementsMatch(t, []uint{gta.ID, witcher.ID}, gameIDs("/api/games?platform=nintendo-switch,playstation5"))
+	assert.ElementsMatch(t, []uint{gta.ID, witcher.ID, local.ID}, gameIDs("/api/games?platform=pc"))
+
+	// Post listings and search follow the platforms of the posts' games
+	gtaPost := createTestPost(t, r, token, map[string]string{"title": "Heist tips", "content": "Crew setup", "game_id": fmt.Sprint(gta.ID)})
+	witcherPost := createTestPost(t, r, token, map[string]string{"title": "Switch port tips", "content": "Handheld settings", "game_id": fmt.Sprint(witcher.ID)})
+
+	postIDs := func(path string) []uint {
+		var resp struct{ Posts []models.Post }
+		w := doJSON(r, "GET", path, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var ids []uint
+		for _, post := range resp.Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []uint{witcherPost}, postIDs("/api/posts?platform=nintendo-switch"))
+	assert.ElementsMatch(t, []uint{gtaPost, witcherPost}, postIDs("/api/posts?platform=pc"))
+	assert.ElementsMatch(t, []uint{gtaPost}, postIDs("/api/posts/search?q=tips&platform=playstation5"))
+	assert.Empty(t, postIDs(fmt.Sprintf("/api/posts/game/%d?platform=nintendo-switch", gta.ID)))
+
+	// Moderators edit the platforms of local games only
+	path := fmt.Sprintf("/api/games/%d/platforms", local.ID)
+	w = doJSON(r, "PUT", path, token, map[string]string{"platforms": "Nintendo Switch"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, "PUT", fmt.Sprintf("/api/games/%d/platforms", gta.ID), modToken, map[string]string{"platforms": "PC"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(r, "PUT", path, modToken, map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(r, "PUT", path, modToken, map[string]string{"platforms": "nintendo switch"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &local)
+	if assert.Len(t, local.Platforms, 1) {
+		assert.Equal(t, "nintendo-switch", local.Platforms[0].Slug)
+	}
+	assert.ElementsMatch(t, []uint{witcher.ID, local.ID}, gameIDs("/api/games?platform=nintendo-switch"))
+
+	w = doJSON(r, "PUT", path, modToken, map[string]string{"platforms": ""})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"platforms":[]`)
+
+	// Names are unique too, so RAWG entries reuse local ones named alike under other slugs
+	var console models.Game
+	w = doJSON(r, "POST", "/api/games", token, map[string]string{"title": "Console Tactics", "platforms": "PlayStation 4"})
+	json.Unmarshal(w.Body.Bytes(), &console)
+	server.AddGame(models.RAWGGame{ID: 58175, Name: "God of War", Slug: "god-of-war",
+		Genres:    []models.RAWGTag{{ID: 40, Name: "Action", Slug: "action-adventure"}},
+		Platforms: []models.RAWGPlatform{rawgPlatform(18, "PlayStation 4", "playstation4")}})
+	var gow models.Game
+	w = doJSON(r, "POST", "/api/games/rawg/import", token, map[string]int{"rawg_id": 58175})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &gow)
+	if assert.Len(t, gow.Platforms, 1) && assert.Len(t, console.Platforms, 1) {
+		assert.Equal(t, console.Platforms[0].ID, gow.Platforms[0].ID)
+	}
+	if assert.Len(t, gow.Tags, 1) {
+		assert.Equal(t, "action", gow.Tags[0].Slug)
+	}
+	w = doJSON(r, "POST", fmt.Sprintf("/api/games/%d/sync", gow.ID), modToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGameDetail(t *testing.T) {
+	r := setupTestRouter(t)
+	alice := registerTestUser(t, r, "detailalice", "")
+	bob := registerTestUser(t, r, "detailbob", "")
+
+	w := doJSON(r, "POST", "/api/games", alice, map[string]string{"title": "Detail Quest", "tags": "RPG", "platforms": "PC"})
+	var game models.Game
+	json.Unmarshal(w.Body.Bytes(), &game)
+	otherID := createTestGame(t, r, alice, "Other Quest", "")
+
+	type detail struct {
+		Game        models.Game
+		Stats       handlers.GameStats
+		RecentPosts []models.Post `json:"recent_posts"`
+		Pagination  struct {
+			Total      int64
+			NextCursor string `json:"next_cursor"`
+		}
+	}
+	getDetail := func(path string) (d detail) {
+		w := doJSON(r, "GET", path, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &d)
+		return d
+	}
+
+	// A game without posts has empty stats
+	d := getDetail(fmt.Sprintf("/api/games/%d", game.ID))
+	assert.Equal(t, "Detail Quest", d.Game.Title)
+	assert.Len(t, d.Game.Tags, 1)
+	assert.Len(t, d.Game.Platforms, 1)
+	assert.Zero(t, d.Stats.PostCount)
+	assert.Nil(t, d.Stats.LatestActivityAt)
+	assert.Empty(t, d.Stats.TopContributors)
+	assert.Empty(t, d.RecentPosts)
+
+	var postIDs []uint
+	for i := 0; i < 3; i++ {
+		postIDs = append(postIDs, createTestPost(t, r, alice, map[string]string{
+			"title": fmt.Sprintf("Detail post %d", i), "content": "Discuss", "game_id": fmt.Sprint(game.ID),
+		}))
+	}
+	// Cross-posted threads count for every linked game
+	crossID := createTestPost(t, r, bob, map[string]string{
+		"title": "Cross post", "content": "Both games", "game_id": fmt.Sprint(otherID), "game_ids": fmt.Sprint(game.ID),
+	})
+	createTestComment(t, r, bob, postIDs[0], nil, "First")
+	createTestComment(t, r, bob, postIDs[1], nil, "Second")
+	createTestComment(t, r, bob, postIDs[1], nil, "Third")
+	deletedID := createTestComment(t, r, alice, postIDs[2], nil, "Gone")
+	doJSON(r, "DELETE", fmt.Sprintf("/api/comments/%d", deletedID), alice, nil)
+	lastID := createTestComment(t, r, alice, crossID, nil, "Latest")
+
+	d = getDetail(fmt.Sprintf("/api/games/slug/%s?limit=2", game.Slug))
+	assert.Equal(t, game.ID, d.Game.ID)
+	assert.Equal(t, int64(4), d.Stats.PostCount)
+	assert.Equal(t, int64(4), d.Stats.CommentCount)
+	var last models.Comment
+	database.GetDB().First(&last, lastID)
+	if assert.NotNil(t, d.Stats.LatestActivityAt) {
+		assert.True(t, d.Stats.LatestActivityAt.Equal(last.CreatedAt))
+	}
+	if assert.Len(t, d.Stats.TopContributors, 2) {
+		assert.Equal(t, "detailalice", d.Stats.TopContributors[0].User.Username)
+		assert.Equal(t, int64(3), d.Stats.TopContributors[0].PostCount)
+		assert.Equal(t, int64(1), d.Stats.TopContributors[0].CommentCount)
+		assert.Equal(t, "detailbob", d.Stats.TopContributors[1].User.Username)
+		assert.Equal(t, int64(1), d.Stats.TopContributors[1].PostCount)
+		assert.Equal(t, int64(3), d.Stats.TopContributors[1].CommentCount)
+	}
+
+	// Recent posts are paginated, newest first
+	if assert.Len(t, d.RecentPosts, 2) {
+		assert.Equal(t, crossID, d.RecentPosts[0].ID)
+		assert.Equal(t, postIDs[2], d.RecentPosts[1].ID)
+	}
+	assert.Equal(t, int64(4), d.Pagination.Total)
+	assert.NotEmpty(t, d.Pagination.NextCursor)
+	d = getDetail(fmt.Sprintf("/api/games/%d?limit=2&cursor=%s", game.ID, d.Pagination.NextCursor))
+	if assert.Len(t, d.RecentPosts, 2) {
+		assert.Equal(t, postIDs[1], d.RecentPosts[0].ID)
+		assert.Equal(t, postIDs[0], d.RecentPosts[1].ID)
+	}
+	assert.Empty(t, d.Pagination.NextCursor)
+
+	w = doJSON(r, "GET", "/api/games/9999", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(r, "GET", "/api/games/slug/no-such-game", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
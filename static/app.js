@@ -460,34 +460,98 @@ function hideAppointModeratorModal() {
 }
 
 // Comments
-async function loadComments(postId) {
+async function loadComments(postId, cursor = null) {
     try {
-        const response = await fetch(`/api/comments/post/${postId}`);
+        const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+        const response = await fetch(`/api/comments/post/${postId}${query}`);
         if (response.ok) {
-            const comments = await response.json();
-            displayComments(comments);
+            const data = await response.json();
+            displayComments(data.comments, cursor !== null);
+            setLoadMoreComments(postId, data.pagination?.next_cursor);
         }
     } catch (error) {
         console.error('Comments load error:', error);
     }
 }
 
-function displayComments(comments, container = null) {
-    const commentsList = container || document.getElementById('commentsList');
+function displayComments(comments, append = false) {
+    const commentsList = document.getElementById('commentsList');
     
     if (!comments || comments.length === 0) {
-        if (!container) {
+        if (!append) {
             commentsList.innerHTML = '<div class="empty-state">No comments yet. Be the first to comment!</div>';
         }
         return;
     }
 
-    commentsList.innerHTML = comments.map(comment => renderComment(comment)).join('');
+    const html = comments.map(comment => renderComment(comment)).join('');
+    if (append) {
+        commentsList.insertAdjacentHTML('beforeend', html);
+    } else {
+        commentsList.innerHTML = html;
+    }
+}
+
+// Shows a button for the next page of top-level comments, if there is one
+function setLoadMoreComments(postId, cursor) {
+    document.getElementById('loadMoreComments')?.remove();
+    if (!cursor) return;
+
+    document.getElementById('commentsList').insertAdjacentHTML('beforeend',
+        '<button class="btn btn-secondary btn-small" id="loadMoreComments">Load more comments</button>');
+    document.getElementById('loadMoreComments').addEventListener('click', () => loadComments(postId, cursor));
+}
+
+// Loads replies the server cut off, from either a replies page or a continued thread
+async function loadMoreReplies(button) {
+    const url = button.dataset.url;
+    const depth = Number(button.dataset.depth);
+    button.disabled = true;
+
+    try {
+        const response = await fetch(url);
+        if (!response.ok) {
+            button.disabled = false;
+            return;
+        }
+        const data = await response.json();
+        button.insertAdjacentHTML('beforebegin', (data.replies || []).map(reply => renderComment(reply, depth)).join(''));
+
+        let next = null;
+        if (data.pagination) {
+            if (data.pagination.next_cursor) {
+                const nextURL = new URL(url, window.location.origin);
+                nextURL.searchParams.set('cursor', data.pagination.next_cursor);
+                next = nextURL.pathname + nextURL.search;
+            }
+        } else if (data.has_more_replies) {
+            next = data.more_replies_url || data.continue_thread_url;
+        }
+
+        if (next) {
+            button.dataset.url = next;
+            button.disabled = false;
+        } else {
+            button.remove();
+        }
+    } catch (error) {
+        console.error('Replies load error:', error);
+        button.disabled = false;
+    }
 }
 
 function renderComment(comment, depth = 0) {
+    // The accepted answer is pinned first, so its place in the thread only links to it
+    if (comment.pinned_above) {
+        return `
+            <div class="comment" data-comment-id="${comment.id}">
+                <div class="comment-date">✅ Accepted answer, pinned above</div>
+            </div>
+        `;
+    }
+
     const maxDepth = 4;
-    const canReply = depth < maxDepth && state.currentUser;
+    const canReply = depth < maxDepth && state.currentUser && !comment.is_deleted;
     
     return `
         <div class="comment" data-comment-id="${comment.id}">
@@ -498,7 +562,7 @@ function renderComment(comment, depth = 0) {
             <div class="comment-content">${escapeHtml(comment.content)}</div>
             <div class="comment-actions">
                 ${canReply ? `<button class="comment-action" onclick="showReplyForm(${comment.id})">↩️ Reply</button>` : ''}
-                ${state.currentUser && (state.currentUser.id === comment.user_id || state.currentUser.role === 'moderator' || state.currentUser.role === 'owner') ? `
+                ${state.currentUser && !comment.is_deleted && (state.currentUser.id === comment.user_id || state.currentUser.role === 'moderator' || state.currentUser.role === 'owner') ? `
                     <button class="comment-action" onclick="deleteComment(${comment.id})">🗑️ Delete</button>
                 ` : ''}
             </div>
@@ -509,9 +573,12 @@ function renderComment(comment, depth = 0) {
                     <button class="btn btn-secondary btn-small" onclick="hideReplyForm(${comment.id})">Cancel</button>
                 </div>
             </div>
-            ${comment.replies && comment.replies.length > 0 ? `
+            ${(comment.replies && comment.replies.length > 0) || comment.has_more_replies ? `
                 <div class="comment-replies">
-                    ${comment.replies.map(reply => renderComment(reply, depth + 1)).join('')}
+                    ${(comment.replies || []).map(reply => renderComment(reply, depth + 1)).join('')}
+                    ${comment.has_more_replies ? `
+                        <button class="comment-action" data-url="${escapeHtml(comment.more_replies_url || comment.continue_thread_url)}" data-depth="${depth + 1}" onclick="loadMoreReplies(this)">↪️ Load more replies</button>
+                    ` : ''}
                 </div>
             ` : ''}
         </div>